@@ -0,0 +1,380 @@
+package httpfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Tar writes a tar archive of the file tree rooted at root to w, streaming
+// file contents as they are read. Entries are named relative to root, or by
+// their base name if root is a file, and keep their modes and modtimes.
+// Directories, including empty ones, get entries of their own, and symbolic
+// links are archived as links rather than followed.
+func (filer *Httpfs) Tar(w io.Writer, root string) error {
+	root = path.Clean("/" + root)
+	tw := tar.NewWriter(w)
+	err := filer.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := archiveName(root, name, info)
+		if rel == "" {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = filer.Readlink(name)
+			if err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return filer.copyTo(tw, name)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// archiveName returns the name in an archive of the tree rooted at root of
+// the file name whose FileInfo is info, or "" for root itself if it is a
+// directory. Directory names end in a slash.
+func archiveName(root, name string, info os.FileInfo) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+	if name == root {
+		if info.IsDir() {
+			return ""
+		}
+		rel = path.Base(name)
+	}
+	if info.IsDir() {
+		rel += "/"
+	}
+	return rel
+}
+
+// copyTo copies the contents of the file name to w.
+func (filer *Httpfs) copyTo(w io.Writer, name string) error {
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Untar unpacks the tar archive read from r under the directory dest,
+// creating dest and any missing parents as needed. Directories, regular files
+// and symbolic links are unpacked with their modes and modtimes, and other
+// entries are skipped. Hard links are unpacked as copies on filers without
+// hard links. An entry whose name would place it outside dest, a symbolic
+// link whose target is absolute or outside dest, or an entry to be written
+// through a symbolic link unpacked before it fails the unpacking with a
+// *os.PathError wrapping os.ErrInvalid; entries before it are left in place.
+func (filer *Httpfs) Untar(r io.Reader, dest string) error {
+	u := &unpacker{filer: filer, op: "untar", dest: path.Clean("/" + dest)}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return u.finish()
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			err = u.link(hdr.Name, hdr.Linkname)
+		} else {
+			err = u.add(hdr.Name, hdr.FileInfo(), hdr.Linkname, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Unzip unpacks the zip archive of size bytes read from r under the
+// directory dest as Untar does.
+func (filer *Httpfs) Unzip(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	u := &unpacker{filer: filer, op: "unzip", dest: path.Clean("/" + dest)}
+	for _, f := range zr.File {
+		err = u.addZip(f)
+		if err != nil {
+			return err
+		}
+	}
+	return u.finish()
+}
+
+// unpacker creates the entries of an archive under dest.
+type unpacker struct {
+	filer *Httpfs
+	op    string
+	dest  string
+
+	// dirs are the directories unpacked, whose modtimes are set once their
+	// contents are written.
+	dirs []unpackedDir
+	// links are the paths unpacked as symbolic links, which later entries
+	// may not be written through.
+	links map[string]bool
+}
+
+type unpackedDir struct {
+	name    string
+	modTime time.Time
+}
+
+func (u *unpacker) addZip(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	info := f.FileInfo()
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		link = string(target)
+	}
+	return u.add(f.Name, info, link, rc)
+}
+
+// add unpacks the entry name, whose FileInfo is info, reading the contents of
+// regular files from r. Symbolic links point to link.
+func (u *unpacker) add(name string, info os.FileInfo, link string, r io.Reader) error {
+	clean, err := cleanPath(u.op, name)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsRune(name, 0) {
+		return &os.PathError{Op: u.op, Path: name, Err: os.ErrInvalid}
+	}
+	target := path.Join(u.dest, clean)
+	if u.throughLink(target) {
+		return &os.PathError{Op: u.op, Path: name, Err: os.ErrInvalid}
+	}
+
+	mode := info.Mode()
+	if mode.IsDir() {
+		err = u.filer.MkdirAll(target, 0755)
+		if err == nil {
+			err = u.filer.Chmod(target, mode.Perm())
+		}
+		u.dirs = append(u.dirs, unpackedDir{target, info.ModTime()})
+		return err
+	}
+	if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+		return nil
+	}
+
+	err = u.filer.MkdirAll(path.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	if mode&os.ModeSymlink != 0 {
+		if !u.inDest(clean, link) {
+			return &os.PathError{Op: u.op, Path: name, Err: os.ErrInvalid}
+		}
+		err = u.filer.Symlink(link, target)
+		if err == nil {
+			if u.links == nil {
+				u.links = make(map[string]bool)
+			}
+			u.links[target] = true
+		}
+		return err
+	}
+
+	f, err := u.filer.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = u.filer.Chmod(target, mode.Perm())
+	}
+	if err == nil {
+		err = u.filer.Chtimes(target, info.ModTime(), info.ModTime())
+	}
+	return err
+}
+
+// inDest reports whether the symbolic link target of the entry clean stays
+// under dest. Backslashes in target are taken for separators, as some filers
+// would.
+func (u *unpacker) inDest(clean, target string) bool {
+	target = strings.ReplaceAll(target, `\`, "/")
+	if path.IsAbs(target) {
+		return false
+	}
+	_, err := cleanPath(u.op, path.Dir(clean)+"/"+target)
+	return err == nil
+}
+
+// throughLink reports whether name, or any directory under dest holding it,
+// was unpacked as a symbolic link, so that writing name would follow it.
+func (u *unpacker) throughLink(name string) bool {
+	for ; name != u.dest && name != "/"; name = path.Dir(name) {
+		if u.links[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// link unpacks the entry name as a hard link to the entry target, unpacked
+// before it, or as a copy of it if the filer has no hard links.
+func (u *unpacker) link(name, target string) error {
+	clean, err := cleanPath(u.op, name)
+	if err != nil {
+		return err
+	}
+	cleanTarget, err := cleanPath(u.op, target)
+	if err != nil {
+		return err
+	}
+	newname, oldname := path.Join(u.dest, clean), path.Join(u.dest, cleanTarget)
+	if u.throughLink(newname) || u.throughLink(path.Dir(oldname)) {
+		return &os.PathError{Op: u.op, Path: name, Err: os.ErrInvalid}
+	}
+
+	err = u.filer.MkdirAll(path.Dir(newname), 0755)
+	if err != nil {
+		return err
+	}
+	err = u.filer.Link(oldname, newname)
+	if errors.Is(err, ErrNotSupported) {
+		err = u.filer.Copy(oldname, newname)
+	}
+	return err
+}
+
+// finish sets the modtimes of the unpacked directories in reverse order, so
+// that those of children are set before those of their parents.
+func (u *unpacker) finish() error {
+	for i := len(u.dirs) - 1; i >= 0; i-- {
+		d := u.dirs[i]
+		err := u.filer.Chtimes(d.name, d.modTime, d.modTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ZipHandler returns a handler answering GET and HEAD requests with a zip
+// archive of the file tree named by the request path under root, as an
+// attachment named after its last path element. The archive is streamed as
+// it is written. Requests for files that do not exist are answered with 404
+// Not Found, and archives of directories honor the listing authorizer.
+func (filer *Httpfs) ZipHandler(root string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := path.Join("/", root, path.Clean("/"+r.URL.Path))
+		info, err := filer.Stat(name)
+		if err != nil {
+			filer.serveError(w, r, err)
+			return
+		}
+		if info.IsDir() && !filer.mayList(r, name) {
+			filer.serveStatus(w, r, http.StatusForbidden, nil)
+			return
+		}
+
+		base := path.Base(name)
+		if base == "/" {
+			base = "archive"
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": base + ".zip"}))
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := filer.writeZip(w, name); err != nil {
+			// The status is gone already: abort the response so that the
+			// client sees a truncated download rather than a bad archive.
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// writeZip writes a zip archive of the file tree rooted at root to w, naming
+// entries as Tar does.
+func (filer *Httpfs) writeZip(w io.Writer, root string) error {
+	zw := zip.NewWriter(w)
+	err := filer.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := archiveName(root, name, info)
+		if rel == "" {
+			return nil
+		}
+
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.Mode().IsRegular() {
+			hdr.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := filer.Readlink(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, link)
+			return err
+		case info.Mode().IsRegular():
+			return filer.copyTo(fw, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,293 @@
+package httpfs_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestTar(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/site/index.html":    "index",
+		"/site/css/main.css":  "body{}",
+		"/site/other.txt":     "not archived",
+		"/elsewhere/file.txt": "not archived",
+	}))
+	if err := fs.Mkdir("/site/empty", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chmod("/site/index.html", 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := fs.Chtimes("/site/index.html", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/site/other.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := fs.Tar(&buf, "/site"); err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		mode os.FileMode
+		data string
+	}
+	got := make(map[string]entry)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[hdr.Name] = entry{hdr.FileInfo().Mode(), string(data)}
+		if hdr.Name == "index.html" && !hdr.ModTime.Equal(mtime) {
+			t.Errorf("index.html modtime = %v, want %v", hdr.ModTime, mtime)
+		}
+	}
+
+	want := map[string]entry{
+		"css/":         {os.ModeDir | 0755, ""},
+		"css/main.css": {0644, "body{}"},
+		"empty/":       {os.ModeDir | 0700, ""},
+		"index.html":   {0600, "index"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("archive = %v, want %v", got, want)
+	}
+}
+
+func TestUntar(t *testing.T) {
+	src := httpfs.New(newMemFS(t, map[string]string{
+		"/site/index.html":   "index",
+		"/site/css/main.css": "body{}",
+	}))
+	if err := src.Chmod("/site/index.html", 0600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := src.Tar(&buf, "/site"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := httpfs.New(newMemFS(t, nil))
+	if err := fs.Untar(&buf, "/restored"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"/restored/index.html":   "index",
+		"/restored/css/main.css": "body{}",
+	} {
+		if got := readFile(t, fs, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if info, err := fs.Stat("/restored/index.html"); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Stat(index.html) = %v, %v; want mode 0600", info, err)
+	}
+}
+
+func TestUntarTraversal(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "a/../../evil.txt", "/../evil.txt"} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 4})
+		tw.Write([]byte("evil"))
+		tw.Close()
+
+		fs := httpfs.New(newMemFS(t, nil))
+		if err := fs.MkdirAll("/dest", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Untar(&buf, "/dest"); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("Untar of %q = %v, want os.ErrInvalid", name, err)
+		}
+		if _, err := fs.Stat("/evil.txt"); !os.IsNotExist(err) {
+			t.Errorf("Untar of %q wrote outside dest: %v", name, err)
+		}
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string]string{
+		"docs/a.txt":   "a",
+		"docs/b/c.txt": "c",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	zw.Close()
+
+	fs := httpfs.New(newMemFS(t, nil))
+	if err := fs.Unzip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "/dest"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fs, "/dest/docs/b/c.txt"); got != "c" {
+		t.Errorf("c.txt = %q", got)
+	}
+
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	w, _ := zw.Create("../../evil.txt")
+	w.Write([]byte("evil"))
+	zw.Close()
+	if err := fs.Unzip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "/dest/docs"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Unzip of a zip-slip entry = %v, want os.ErrInvalid", err)
+	}
+	if _, err := fs.Stat("/evil.txt"); !os.IsNotExist(err) {
+		t.Errorf("Unzip wrote outside dest: %v", err)
+	}
+}
+
+func TestZipHandler(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/public/docs/a.txt":     "a",
+		"/public/docs/sub/b.txt": "bb",
+		"/private/secret.txt":    "secret",
+	}))
+	if err := fs.Mkdir("/public/docs/empty", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("/public/nothing", 0755); err != nil {
+		t.Fatal(err)
+	}
+	h := fs.ZipHandler("/public")
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+	read := func(w *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]string)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+
+	w := get("/docs")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=docs.zip` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	want := map[string]string{
+		"a.txt":     "a",
+		"empty/":    "",
+		"sub/":      "",
+		"sub/b.txt": "bb",
+	}
+	if got := read(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive = %v, want %v", got, want)
+	}
+
+	if w := get("/nothing"); w.Code != http.StatusOK || len(read(w)) != 0 {
+		t.Errorf("empty directory: status %d, %d entries", w.Code, len(read(w)))
+	}
+	if w := get("/../private"); w.Code != http.StatusNotFound {
+		t.Errorf("outside root: status %d", w.Code)
+	}
+	if w := get("/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("missing: status %d", w.Code)
+	}
+}
+
+func TestUntarHardLink(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0644, Size: 1})
+	tw.Write([]byte("a"))
+	tw.WriteHeader(&tar.Header{Name: "sub/b.txt", Typeflag: tar.TypeLink, Linkname: "a.txt"})
+	tw.Close()
+
+	// memfs has no hard links, so the link is unpacked as a copy.
+	fs := httpfs.New(newMemFS(t, nil))
+	if err := fs.Untar(&buf, "/dest"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fs, "/dest/sub/b.txt"); got != "a" {
+		t.Errorf("linked file = %q, want %q", got, "a")
+	}
+}
+
+func TestUntarSymlinks(t *testing.T) {
+	type entry struct{ name, link, data string }
+	untar := func(entries ...entry) (*httpfs.Httpfs, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, e := range entries {
+			if e.link != "" {
+				tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: tar.TypeSymlink, Linkname: e.link, Mode: 0777})
+				continue
+			}
+			tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data))})
+			tw.Write([]byte(e.data))
+		}
+		tw.Close()
+
+		fs := httpfs.New(&symlinkFS{Filer: newMemFS(t, nil), links: map[string]string{}},
+			httpfs.WithAllowSymlinkCreation(true))
+		return fs, fs.Untar(&buf, "/dest")
+	}
+
+	if _, err := untar(entry{name: "a.txt", data: "a"}, entry{name: "sub/link", link: "../a.txt"}); err != nil {
+		t.Errorf("Untar of a link within dest: %v", err)
+	}
+	for _, link := range []string{"/etc", "../../x", "sub/../../x", `..\..\x`} {
+		if _, err := untar(entry{name: "link", link: link}); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("Untar of a link to %q = %v, want os.ErrInvalid", link, err)
+		}
+	}
+
+	fs, err := untar(
+		entry{name: "dir/a.txt", data: "a"},
+		entry{name: "link", link: "dir"},
+		entry{name: "link/evil.txt", data: "evil"},
+	)
+	if !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Untar writing through a link = %v, want os.ErrInvalid", err)
+	}
+	for _, name := range []string{"/dest/link/evil.txt", "/dest/dir/evil.txt"} {
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Untar wrote %s through a link: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package httpfs
+
+import (
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"syscall"
+
+	"github.com/absfs/absfs"
+)
+
+// renamer is implemented by filers that can rename files.
+type renamer interface {
+	Rename(oldpath, newpath string) error
+}
+
+// WithTempDir sets the directory in which atomic writes create their
+// temporary files. By default they are created next to the file being
+// written. A rename from another directory may not be atomic on every filer.
+func WithTempDir(dir string) Option {
+	return func(filer *Httpfs) {
+		filer.tempDir = dir
+	}
+}
+
+// WriteFileAtomic writes data to a temporary file and then renames it over
+// name, so that name never holds partially written data. If the underlying
+// filer cannot rename files, it falls back to writing name directly, which
+// is not atomic.
+func (filer *Httpfs) WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	if err := filer.checkWrite(name); err != nil {
+		return err
+	}
+	r, ok := filer.fs.(renamer)
+	if !ok {
+		return filer.writeFile(name, data, perm)
+	}
+
+	tmp, f, err := filer.createTemp(name, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = filer.rename(r, tmp, name)
+	}
+	if err != nil {
+		filer.Remove(tmp)
+	}
+	return err
+}
+
+// writeFile writes data to the file name, creating it with perm if needed.
+func (filer *Httpfs) writeFile(name string, data []byte, perm os.FileMode) error {
+	f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// createTemp creates a new temporary file for writing name, in the configured
+// temp directory or else next to name. It fails with os.ErrExist if it finds
+// no free name.
+func (filer *Httpfs) createTemp(name string, perm os.FileMode) (string, absfs.File, error) {
+	dir := filer.tempDir
+	if dir == "" {
+		dir = path.Dir(path.Clean("/" + name))
+	}
+
+	for i := 0; i < maxTempAttempts; i++ {
+		tmp := tempPath(dir, name)
+		p, err := filer.resolve("open", tmp)
+		if err != nil {
+			return "", nil, err
+		}
+		f, err := filer.fs.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err == nil && filer.quota != nil {
+			f = newQuotaFile(f, filer.quota, tmp, p, os.O_WRONLY, 0)
+		}
+		return tmp, f, err
+	}
+	return "", nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+}
+
+// maxTempAttempts bounds the random names createTemp tries, in case the
+// filer reports every name as existing.
+const maxTempAttempts = 100
+
+// confined returns an Httpfs rooted at root in the underlying filer, the
+// tree that will become the directory name of filer, with the write
+// restrictions, quota, concurrency limit and instrumentation of filer. Its
+// files are charged to the quota by their paths in the underlying filer, so
+// that the charges follow the tree when it is renamed into place.
+func (filer *Httpfs) confined(root, name string) *Httpfs {
+	tree := &Httpfs{
+		fs:            &chroot{fs: filer.fs, dir: root},
+		globBase:      path.Join(filer.globBase, path.Clean("/"+filer.slashPath(name))),
+		noSlashPaths:  filer.noSlashPaths,
+		hideDotfiles:  filer.hideDotfiles,
+		allowSymlinks: filer.allowSymlinks,
+		denyGlobs:     filer.denyGlobs,
+		allowedExts:   filer.allowedExts,
+		readOnly:      filer.readOnly,
+		strictSync:    filer.strictSync,
+		syncOnClose:   filer.syncOnClose,
+		slowThreshold: filer.slowThreshold,
+		observer:      filer.observer,
+		retries:       filer.retries,
+		sem:           filer.sem,
+		semTimeout:    filer.semTimeout,
+	}
+	if filer.quota != nil {
+		tree.quota = filer.quota.within(root)
+	}
+	return tree
+}
+
+// tempPath returns a random hidden path in dir for a temporary copy of name.
+func tempPath(dir, name string) string {
+	return path.Join(dir, "."+path.Base(name)+".tmp"+strconv.FormatUint(rand.Uint64(), 36))
+}
+
+// ReplaceDir replaces the contents of the directory name. It creates a
+// temporary sibling directory, calls populate with an Httpfs rooted at it to
+// fill it in, and then swaps it into place by renaming name aside, renaming
+// the new directory in and removing the old one. If populate or the swap
+// fails, name is left as it was. The underlying filer must support renaming
+// directories. The Httpfs given to populate writes under the same quota,
+// concurrency limit and restrictions as filer, with deny globs matched
+// against the names its files will have once swapped in.
+func (filer *Httpfs) ReplaceDir(name string, populate func(tmp *Httpfs) error) error {
+	r, ok := filer.fs.(renamer)
+	if !ok {
+		return &os.PathError{Op: "rename", Path: name, Err: ErrNotImplemented}
+	}
+	name = path.Clean("/" + name)
+	dir := path.Dir(name)
+
+	perm := os.FileMode(0755)
+	info, err := filer.Stat(name)
+	switch {
+	case err == nil && !info.IsDir():
+		return &os.PathError{Op: "replace", Path: name, Err: syscall.ENOTDIR}
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	tmp := tempPath(dir, name)
+	err = filer.Mkdir(tmp, perm)
+	if err != nil {
+		return err
+	}
+	root, err := filer.resolve("replace", tmp)
+	if err != nil {
+		return err
+	}
+	err = populate(filer.confined(root, name))
+	if err != nil {
+		filer.removeAll(tmp)
+		return err
+	}
+
+	var old string
+	if info != nil {
+		old = tempPath(dir, name)
+		err = filer.rename(r, name, old)
+		if err != nil {
+			filer.removeAll(tmp)
+			return err
+		}
+	}
+	err = filer.rename(r, tmp, name)
+	if err != nil {
+		if old != "" {
+			filer.rename(r, old, name)
+		}
+		filer.removeAll(tmp)
+		return err
+	}
+	if old != "" {
+		return filer.removeAll(old)
+	}
+	return nil
+}
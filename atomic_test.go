@@ -0,0 +1,347 @@
+package httpfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// renameFS adds Rename to a filer by copying and removing, recording the
+// contents of each source file at the moment it is renamed.
+type renameFS struct {
+	absfs.Filer
+	renamed map[string]string
+}
+
+func (fs *renameFS) Rename(oldpath, newpath string) error {
+	info, err := fs.Stat(oldpath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fs.renameDir(oldpath, newpath, info.Mode())
+	}
+
+	src, err := fs.OpenFile(oldpath, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	fs.renamed[oldpath] = string(data)
+
+	dst, err := fs.OpenFile(newpath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+	dst.Close()
+	if err != nil {
+		return err
+	}
+	return fs.Remove(oldpath)
+}
+
+func (fs *renameFS) renameDir(oldpath, newpath string, mode os.FileMode) error {
+	err := fs.Mkdir(newpath, mode.Perm())
+	if err != nil {
+		return err
+	}
+	entries, err := httpfs.New(fs.Filer).ReadDir(oldpath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err = fs.Rename(path.Join(oldpath, e.Name()), path.Join(newpath, e.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return fs.Remove(oldpath)
+}
+
+func readFile(t *testing.T, fs *httpfs.Httpfs, name string) string {
+	t.Helper()
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteFileAtomicTempDir(t *testing.T) {
+	for _, tempDir := range []string{"", "/tmp"} {
+		rfs := &renameFS{
+			Filer:   newMemFS(t, map[string]string{"/site/page.html": "old", "/tmp/.keep": ""}),
+			renamed: map[string]string{},
+		}
+		fs := httpfs.New(rfs, httpfs.WithTempDir(tempDir))
+
+		err := fs.WriteFileAtomic("/site/page.html", []byte("new"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rfs.renamed) != 1 {
+			t.Fatalf("renamed = %v", rfs.renamed)
+		}
+		wantDir := tempDir
+		if wantDir == "" {
+			wantDir = "/site"
+		}
+		for tmp, data := range rfs.renamed {
+			if path.Dir(tmp) != wantDir {
+				t.Errorf("temp file %s not in %s", tmp, wantDir)
+			}
+			if data != "new" {
+				t.Errorf("temp file held %q at rename", data)
+			}
+			if _, err := fs.Stat(tmp); !os.IsNotExist(err) {
+				t.Errorf("temp file %s left behind", tmp)
+			}
+		}
+		if got := readFile(t, fs, "/site/page.html"); got != "new" {
+			t.Errorf("content = %q", got)
+		}
+	}
+}
+
+func TestReplaceDir(t *testing.T) {
+	rfs := &renameFS{
+		Filer: newMemFS(t, map[string]string{
+			"/site/index.html": "v1",
+			"/site/old.css":    "old",
+		}),
+		renamed: map[string]string{},
+	}
+	fs := httpfs.New(rfs)
+
+	errPopulate := errors.New("build failed")
+	err := fs.ReplaceDir("/site", func(tmp *httpfs.Httpfs) error {
+		f, err := tmp.OpenFile("/index.html", os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		return errPopulate
+	})
+	if err != errPopulate {
+		t.Fatalf("err = %v, want %v", err, errPopulate)
+	}
+	if got := readFile(t, fs, "/site/index.html"); got != "v1" {
+		t.Errorf("failed populate changed index.html to %q", got)
+	}
+	assertDir(t, fs, "/", "site")
+
+	err = fs.ReplaceDir("/site", func(tmp *httpfs.Httpfs) error {
+		err := tmp.MkdirAll("/js", 0755)
+		if err != nil {
+			return err
+		}
+		for name, data := range map[string]string{"/index.html": "v2", "/js/app.js": "app"} {
+			f, err := tmp.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			f.Write([]byte(data))
+			f.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fs, "/site/index.html"); got != "v2" {
+		t.Errorf("index.html = %q, want v2", got)
+	}
+	if got := readFile(t, fs, "/site/js/app.js"); got != "app" {
+		t.Errorf("app.js = %q", got)
+	}
+	assertDir(t, fs, "/site", "index.html", "js")
+	assertDir(t, fs, "/", "site")
+}
+
+// assertDir fails unless dir holds exactly names.
+func assertDir(t *testing.T, fs *httpfs.Httpfs, dir string, names ...string) {
+	t.Helper()
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Errorf("%s holds %v, want %v", dir, got, names)
+	}
+}
+
+// writeLogFS records the name of the file each write goes to.
+type writeLogFS struct {
+	absfs.Filer
+	writes []string
+}
+
+func (fs *writeLogFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &writeLogFile{File: f, fs: fs, name: name}, nil
+}
+
+type writeLogFile struct {
+	absfs.File
+	fs   *writeLogFS
+	name string
+}
+
+func (f *writeLogFile) Write(p []byte) (int, error) {
+	f.fs.writes = append(f.fs.writes, f.name)
+	return f.File.Write(p)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	wfs := &writeLogFS{Filer: newMemFS(t, map[string]string{"/site/page.html": "old"})}
+	rfs := &renameFS{Filer: wfs, renamed: map[string]string{}}
+	fs := httpfs.New(rfs)
+
+	if err := fs.WriteFileAtomic("/site/page.html", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// renameFS renames by writing the target, so the only write to
+	// page.html must come after the complete temp file was renamed.
+	if len(rfs.renamed) != 1 {
+		t.Fatalf("renamed = %v", rfs.renamed)
+	}
+	for tmp, data := range rfs.renamed {
+		if data != "new" {
+			t.Errorf("%s held %q when renamed", tmp, data)
+		}
+	}
+	if n := len(wfs.writes); n == 0 || wfs.writes[0] == "/site/page.html" || wfs.writes[n-1] != "/site/page.html" {
+		t.Errorf("writes = %q", wfs.writes)
+	}
+	if got := readFile(t, fs, "/site/page.html"); got != "new" {
+		t.Errorf("content = %q", got)
+	}
+
+	// Without renames the file is written in place.
+	fs = httpfs.New(newMemFS(t, map[string]string{"/site/page.html": "old"}))
+	if err := fs.WriteFileAtomic("/site/page.html", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fs, "/site/page.html"); got != "new" {
+		t.Errorf("content without renames = %q", got)
+	}
+}
+
+func TestReplaceDirRestrictions(t *testing.T) {
+	rfs := &renameFS{Filer: newMemFS(t, map[string]string{"/site/index.html": "v1"}), renamed: map[string]string{}}
+	fs := httpfs.New(rfs,
+		httpfs.WithDenyGlobs("/site/private/*"),
+		httpfs.WithAllowedExtensions(".html"),
+		httpfs.WithQuota(4))
+
+	write := func(tmp *httpfs.Httpfs, name, data string) error {
+		f, err := tmp.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte(data))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	err := fs.ReplaceDir("/site", func(tmp *httpfs.Httpfs) error {
+		if err := tmp.Mkdir("/private", 0755); err != nil {
+			return err
+		}
+		if err := write(tmp, "/private/a.html", "a"); !os.IsPermission(err) {
+			t.Errorf("write matching a deny glob: err = %v", err)
+		}
+		if err := write(tmp, "/app.php", "php"); !os.IsPermission(err) {
+			t.Errorf("write of a disallowed extension: err = %v", err)
+		}
+		if err := write(tmp, "/index.html", "too long"); !errors.Is(err, httpfs.ErrQuotaExceeded) {
+			t.Errorf("write beyond the quota: err = %v", err)
+		}
+		return write(tmp, "/index.html", "v2")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fs, "/site/index.html"); got != "v2" {
+		t.Errorf("index.html = %q, want v2", got)
+	}
+}
+
+func TestReplaceDirQuota(t *testing.T) {
+	rfs := &renameFS{Filer: newMemFS(t, map[string]string{"/site/.keep": ""}), renamed: map[string]string{}}
+	fs := httpfs.New(rfs, httpfs.WithQuota(8))
+
+	// Each tree is charged while it is filled in next to the old one, and
+	// the old one is freed once it is swapped out.
+	for i := 0; i < 3; i++ {
+		err := fs.ReplaceDir("/site", func(tmp *httpfs.Httpfs) error {
+			f, err := tmp.Create("/a.txt")
+			if err != nil {
+				return err
+			}
+			_, err = f.Write([]byte("aaaa"))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatalf("replacement %d: %v", i, err)
+		}
+	}
+
+	if err := fs.CheckUploadPath("/b.txt"); err != nil {
+		t.Errorf("quota after replacing: %v", err)
+	}
+	if err := fs.Truncate("/site/a.txt", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFileAtomic("/b.txt", []byte("bbbbbbbb"), 0644); err != nil {
+		t.Errorf("write after freeing the replaced tree: %v", err)
+	}
+}
+
+// existFS reports every file created exclusively as existing.
+type existFS struct {
+	absfs.Filer
+}
+
+func (fs *existFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if flag&os.O_EXCL != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func (fs *existFS) Rename(oldpath, newpath string) error { return nil }
+
+func TestWriteFileAtomicNoTempName(t *testing.T) {
+	fs := httpfs.New(&existFS{newMemFS(t, nil)})
+	if err := fs.WriteFileAtomic("/a.txt", []byte("a"), 0644); !os.IsExist(err) {
+		t.Errorf("err = %v, want exist", err)
+	}
+}
@@ -0,0 +1,148 @@
+package httpfs
+
+import (
+	"bytes"
+	"container/list"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+)
+
+// cacheMaxFileSize is the largest file kept by the cache set with WithCache.
+const cacheMaxFileSize = 1 << 20
+
+// WithCache keeps the contents of recently opened small files, up to
+// maxBytes in total, in memory. Opening a cached file for reading only stats
+// it in the underlying filer, and files whose size or modtime changed are
+// read again. The least recently used files are evicted first. Files over
+// 1 MiB, or over maxBytes, are never cached.
+func WithCache(maxBytes int64) Option {
+	return func(filer *Httpfs) {
+		if maxBytes <= 0 {
+			filer.cache = nil
+			return
+		}
+		filer.cache = &fileCache{
+			maxBytes: maxBytes,
+			lru:      list.New(),
+			entries:  make(map[string]*list.Element),
+		}
+	}
+}
+
+// fileCache is an LRU cache of file contents.
+type fileCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  fileKey
+	info os.FileInfo
+	data []byte
+}
+
+// cacheable reports whether a file with the given info may be cached.
+func (c *fileCache) cacheable(info os.FileInfo) bool {
+	return info.Mode().IsRegular() && info.Size() <= cacheMaxFileSize && info.Size() <= c.maxBytes
+}
+
+// get returns the cached entry for key, if it is current.
+func (c *fileCache) get(key fileKey) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key.name]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if entry.key != key {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put caches entry, evicting the least recently used entries to make room.
+func (c *fileCache) put(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key.name]; ok {
+		c.remove(elem)
+	}
+	for c.size+int64(len(entry.data)) > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+	c.entries[entry.key.name] = c.lru.PushFront(entry)
+	c.size += int64(len(entry.data))
+}
+
+// forget drops the file name from the cache.
+func (c *fileCache) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path.Clean("/"+name)]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *fileCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key.name)
+	c.size -= int64(len(entry.data))
+}
+
+// openCached opens the file name for reading from the cache, reading it into
+// the cache first if needed. It returns a nil file if name is not cacheable.
+func (filer *Httpfs) openCached(name string) (*memFile, error) {
+	info, err := filer.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !filer.cache.cacheable(info) {
+		return nil, nil
+	}
+	key := newFileKey(path.Clean("/"+name), info)
+	if entry, ok := filer.cache.get(key); ok {
+		return newMemFile(entry), nil
+	}
+
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := bytes.NewBuffer(make([]byte, 0, info.Size()+bytes.MinRead))
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, err
+	}
+	entry := &cacheEntry{key: key, info: info, data: buf.Bytes()}
+	if int64(len(entry.data)) == info.Size() {
+		filer.cache.put(entry)
+	}
+	return newMemFile(entry), nil
+}
+
+// memFile is a read-only http.File over cached contents.
+type memFile struct {
+	*bytes.Reader
+	entry *cacheEntry
+}
+
+func newMemFile(entry *cacheEntry) *memFile {
+	return &memFile{Reader: bytes.NewReader(entry.data), entry: entry}
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Stat() (os.FileInfo, error) { return f.entry.info, nil }
+
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.entry.key.name, Err: syscall.ENOTDIR}
+}
@@ -0,0 +1,100 @@
+package httpfs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// countingOpenFS counts the files opened in the wrapped filer.
+type countingOpenFS struct {
+	absfs.Filer
+	opens atomic.Int64
+}
+
+func (fs *countingOpenFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	fs.opens.Add(1)
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func TestCache(t *testing.T) {
+	cfs := &countingOpenFS{Filer: newMemFS(t, map[string]string{
+		"/a.txt":   "aaaa",
+		"/b.txt":   "bbbb",
+		"/c.txt":   "cccc",
+		"/big.txt": strings.Repeat("x", 64),
+	})}
+	fs := httpfs.New(cfs, httpfs.WithCache(10))
+
+	read := func(name string) string {
+		t.Helper()
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got := read("/a.txt"); got != "aaaa" {
+		t.Fatalf("read %q", got)
+	}
+	opens := cfs.opens.Load()
+	if got := read("/a.txt"); got != "aaaa" {
+		t.Fatalf("cached read %q", got)
+	}
+	if n := cfs.opens.Load() - opens; n != 0 {
+		t.Errorf("cached read opened %d files in the backing filer", n)
+	}
+
+	mtime := time.Now().Add(time.Hour)
+	if err := cfs.Chtimes("/a.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	opens = cfs.opens.Load()
+	read("/a.txt")
+	if n := cfs.opens.Load() - opens; n != 1 {
+		t.Errorf("read after a modtime change opened %d files, want 1", n)
+	}
+
+	opens = cfs.opens.Load()
+	read("/big.txt")
+	read("/big.txt")
+	if n := cfs.opens.Load() - opens; n != 2 {
+		t.Errorf("reads of a file larger than the cache opened %d files, want 2", n)
+	}
+
+	// a.txt and b.txt fill the cache, so reading c.txt evicts a.txt.
+	read("/b.txt")
+	read("/c.txt")
+	opens = cfs.opens.Load()
+	read("/b.txt")
+	read("/c.txt")
+	if n := cfs.opens.Load() - opens; n != 0 {
+		t.Errorf("reads of recently used files opened %d files", n)
+	}
+	read("/a.txt")
+	if n := cfs.opens.Load() - opens; n != 1 {
+		t.Errorf("read of an evicted file opened %d files, want 1", n)
+	}
+
+	f, err := fs.OpenFile("/a.txt", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("AAAA"))
+	f.Close()
+	if got := read("/a.txt"); got != "AAAA" {
+		t.Errorf("read after a write = %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package httpfs
+
+// allRemover is implemented by filers that can remove a file tree at once.
+type allRemover interface {
+	RemoveAll(name string) error
+}
+
+// Capabilities reports the optional operations an underlying filer supports.
+type Capabilities struct {
+	Symlinks  bool
+	HardLinks bool
+	Rename    bool
+	RemoveAll bool
+	Chown     bool
+	Chtimes   bool
+	Truncate  bool
+	Snapshot  bool
+}
+
+// Capabilities reports the operations the underlying filer supports
+// natively, for example to disable the others in a user interface. Some of
+// the others still work, at a cost: Rename copies files, Truncate opens the
+// file, RemoveAll removes files one at a time and Snapshot is best effort.
+// Chown and Chtimes are part of absfs.Filer, so are reported supported
+// unless the filesystem is read-only, as every operation modifying files is
+// then reported unsupported. Symlinks also needs WithAllowSymlinkCreation.
+func (filer *Httpfs) Capabilities() Capabilities {
+	_, snapshots := filer.fs.(snapshotter)
+	caps := Capabilities{Snapshot: snapshots}
+	if filer.readOnly {
+		return caps
+	}
+	if _, ok := filer.fs.(*iofsFiler); ok {
+		return caps
+	}
+
+	_, caps.Symlinks = filer.fs.(symlinker)
+	caps.Symlinks = caps.Symlinks && filer.allowSymlinks
+	_, caps.HardLinks = filer.fs.(linker)
+	_, caps.Rename = filer.fs.(renamer)
+	_, caps.RemoveAll = filer.fs.(allRemover)
+	_, caps.Truncate = filer.fs.(truncater)
+	caps.Chown, caps.Chtimes = true, true
+	return caps
+}
@@ -0,0 +1,55 @@
+package httpfs_test
+
+import (
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+	"github.com/absfs/memfs"
+)
+
+// capableFS supports every optional operation, or claims to.
+type capableFS struct {
+	absfs.Filer
+}
+
+func (fs *capableFS) Symlink(oldname, newname string) error  { return httpfs.ErrNotSupported }
+func (fs *capableFS) Link(oldname, newname string) error     { return httpfs.ErrNotSupported }
+func (fs *capableFS) Rename(oldpath, newpath string) error   { return httpfs.ErrNotSupported }
+func (fs *capableFS) RemoveAll(name string) error            { return httpfs.ErrNotSupported }
+func (fs *capableFS) Truncate(name string, size int64) error { return httpfs.ErrNotSupported }
+func (fs *capableFS) Snapshot() (absfs.Filer, error)         { return fs.Filer, nil }
+
+// strippedFS hides every optional method of a filer.
+type strippedFS struct {
+	absfs.Filer
+}
+
+func TestCapabilities(t *testing.T) {
+	mfs, err := memfs.NewFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := httpfs.Capabilities{
+		Symlinks: true, HardLinks: true, Rename: true, RemoveAll: true,
+		Chown: true, Chtimes: true, Truncate: true, Snapshot: true,
+	}
+	for _, test := range []struct {
+		name string
+		fs   *httpfs.Httpfs
+		want httpfs.Capabilities
+	}{
+		{"capable", httpfs.New(&capableFS{mfs}, httpfs.WithAllowSymlinkCreation(true)), all},
+		{"no symlink creation", httpfs.New(&capableFS{mfs}), httpfs.Capabilities{
+			HardLinks: true, Rename: true, RemoveAll: true,
+			Chown: true, Chtimes: true, Truncate: true, Snapshot: true,
+		}},
+		{"stripped", httpfs.New(&strippedFS{mfs}), httpfs.Capabilities{Chown: true, Chtimes: true}},
+		{"read-only", httpfs.New(&capableFS{mfs}, httpfs.WithReadOnly(true)), httpfs.Capabilities{Snapshot: true}},
+	} {
+		if got := test.fs.Capabilities(); got != test.want {
+			t.Errorf("%s: Capabilities() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
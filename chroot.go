@@ -0,0 +1,47 @@
+package httpfs
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// chroot is a filer rooted at the directory dir of another filer.
+type chroot struct {
+	fs  absfs.Filer
+	dir string
+}
+
+func (c *chroot) path(name string) string {
+	return path.Join(c.dir, path.Clean("/"+name))
+}
+
+func (c *chroot) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	return c.fs.OpenFile(c.path(name), flag, perm)
+}
+
+func (c *chroot) Mkdir(name string, perm os.FileMode) error {
+	return c.fs.Mkdir(c.path(name), perm)
+}
+
+func (c *chroot) Remove(name string) error {
+	return c.fs.Remove(c.path(name))
+}
+
+func (c *chroot) Stat(name string) (os.FileInfo, error) {
+	return c.fs.Stat(c.path(name))
+}
+
+func (c *chroot) Chmod(name string, mode os.FileMode) error {
+	return c.fs.Chmod(c.path(name), mode)
+}
+
+func (c *chroot) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return c.fs.Chtimes(c.path(name), atime, mtime)
+}
+
+func (c *chroot) Chown(name string, uid, gid int) error {
+	return c.fs.Chown(c.path(name), uid, gid)
+}
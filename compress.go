@@ -0,0 +1,213 @@
+package httpfs
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressibleTypes lists the content types compressed by
+// WithResponseCompression when no types are given. Entries ending in "/"
+// match every subtype.
+var DefaultCompressibleTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// incompressibleTypes are never compressed, even when matched by the
+// configured types, because their contents are already compressed.
+var incompressibleTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"font/woff",
+	"font/woff2",
+}
+
+// WithResponseCompression gzip compresses responses on the fly for clients
+// that accept it. Only responses at least minSize bytes long whose content
+// type matches one of types are compressed; with no types,
+// DefaultCompressibleTypes is used. Already compressed types such as images
+// and zip archives are always sent as is. HEAD requests are answered with
+// the headers of the matching GET request, so clients wanting the full
+// length of a file, for example to resume its download with range requests,
+// which are never compressed, must not accept gzip.
+func WithResponseCompression(minSize int64, types ...string) Option {
+	return func(filer *Httpfs) {
+		if len(types) == 0 {
+			types = DefaultCompressibleTypes
+		}
+		filer.compression = &compressor{minSize: minSize, types: types}
+	}
+}
+
+// GzipHandler returns a handler that serves files and listings as ServeHTTP
+// does but gzip compresses responses for clients that accept it, whether or
+// not WithResponseCompression is set. It compresses as configured by
+// WithResponseCompression if set, and otherwise responses of any size whose
+// type is in DefaultCompressibleTypes. Requests other than GET and HEAD are
+// passed to ServeHTTP.
+func (filer *Httpfs) GzipHandler() http.Handler {
+	c := filer.compression
+	if c == nil {
+		c = &compressor{types: DefaultCompressibleTypes}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			filer.ServeHTTP(w, r)
+			return
+		}
+		filer.serveCompressed(w, r, c)
+	})
+}
+
+type compressor struct {
+	minSize int64
+	types   []string
+}
+
+// wrap returns a ResponseWriter that compresses the response written to w if
+// r accepts gzip. Range requests are never compressed, since their byte
+// ranges refer to the uncompressed file. HEAD requests get the headers the
+// same GET request would, compressed or not, and no body. The returned
+// writer must be closed to flush the compressed stream.
+func (c *compressor) wrap(w http.ResponseWriter, r *http.Request) *gzipResponseWriter {
+	addVary(w.Header(), "Accept-Encoding")
+	return &gzipResponseWriter{
+		ResponseWriter: w,
+		c:              c,
+		accept:         acceptsGzip(r) && r.Header.Get("Range") == "",
+		head:           r.Method == http.MethodHead,
+	}
+}
+
+// compressible reports whether a response with header h should be compressed.
+func (c *compressor) compressible(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < c.minSize {
+			return false
+		}
+	}
+	mediatype, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	if matchType(incompressibleTypes, mediatype) && !matchExact(c.types, mediatype) {
+		return false
+	}
+	return matchType(c.types, mediatype)
+}
+
+// matchType reports whether mediatype is in types, where entries ending in
+// "/" match all subtypes.
+func matchType(types []string, mediatype string) bool {
+	for _, t := range types {
+		if t == mediatype || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediatype, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchExact(types []string, mediatype string) bool {
+	for _, t := range types {
+		if t == mediatype {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(enc, ";")
+		if strings.TrimSpace(params[0]) != "gzip" {
+			continue
+		}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter decides whether to compress when the header is written,
+// based on the status, content type and length set by then.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	c      *compressor
+	gz     *gzip.Writer
+	accept bool
+	wrote  bool
+
+	// head is set for HEAD requests, whose body is discarded.
+	head bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	h := w.Header()
+	if w.accept && code == http.StatusOK && w.c.compressible(h) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		// The encoded bytes differ from those a strong ETag vouches for.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		if !w.head {
+			w.gz = gzip.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.head {
+		return len(p), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Close flushes the compressed stream, if any.
+func (w *gzipResponseWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Close()
+}
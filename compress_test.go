@@ -0,0 +1,137 @@
+package httpfs_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestResponseCompression(t *testing.T) {
+	text := strings.Repeat("foo bar bat. ", 100)
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/foo.txt":   text,
+		"/photo.jpg": strings.Repeat("\xff\xd8\xff", 100),
+		"/small.txt": "tiny",
+	}), httpfs.WithResponseCompression(256))
+
+	get := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", name, nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/foo.txt")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("Content-Length = %q, want none", cl)
+	}
+	if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+		t.Errorf("Vary = %q", v)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != text {
+		t.Fatal("wrong decompressed content")
+	}
+
+	// HEAD reports the headers GET does.
+	req := httptest.NewRequest("HEAD", "/foo.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	head := httptest.NewRecorder()
+	fs.ServeHTTP(head, req)
+	for _, h := range []string{"Content-Encoding", "Content-Length", "Vary", "ETag"} {
+		if got, want := head.Header().Get(h), w.Header().Get(h); got != want {
+			t.Errorf("HEAD %s = %q, GET %s = %q", h, got, h, want)
+		}
+	}
+	if head.Code != http.StatusOK || head.Body.Len() != 0 {
+		t.Errorf("HEAD = %d with %d body bytes", head.Code, head.Body.Len())
+	}
+
+	for _, name := range []string{"/photo.jpg", "/small.txt"} {
+		w = get(name)
+		if ce := w.Header().Get("Content-Encoding"); ce != "" {
+			t.Errorf("%s: Content-Encoding = %q, want none", name, ce)
+		}
+		if w.Header().Get("Content-Length") == "" {
+			t.Errorf("%s: missing Content-Length", name)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/foo.txt", nil)
+	w = httptest.NewRecorder()
+	fs.ServeHTTP(w, req)
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("Content-Encoding = %q without Accept-Encoding", ce)
+	}
+	if w.Body.String() != text {
+		t.Error("wrong uncompressed content")
+	}
+}
+
+func TestGzipHandler(t *testing.T) {
+	text := strings.Repeat("foo bar bat. ", 100)
+	h := httpfs.New(newMemFS(t, map[string]string{
+		"/foo.txt":   text,
+		"/photo.jpg": strings.Repeat("\xff\xd8\xff", 100),
+	})).GzipHandler()
+
+	get := func(name, rng string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", name, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/foo.txt", "")
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("Content-Length = %q, want none", cl)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadAll(zr); err != nil || string(data) != text {
+		t.Fatalf("decompressed %d bytes, %v", len(data), err)
+	}
+
+	if ce := get("/photo.jpg", "").Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("image: Content-Encoding = %q, want none", ce)
+	}
+
+	w = get("/foo.txt", "bytes=4-6")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("range: status = %d", w.Code)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("range: Content-Encoding = %q, want none", ce)
+	}
+	if w.Body.String() != "bar" {
+		t.Errorf("range: body = %q, want %q", w.Body.String(), "bar")
+	}
+}
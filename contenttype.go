@@ -0,0 +1,37 @@
+package httpfs
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// WithContentTypeFunc sets a function supplying the Content-Type of served
+// files, for files whose type cannot be told from their extension. When it
+// returns "" the type is detected as usual, from the extension or else the
+// contents.
+func WithContentTypeFunc(contentType func(name string, info os.FileInfo) string) Option {
+	return func(filer *Httpfs) {
+		filer.contentType = contentType
+	}
+}
+
+// WithDefaultCharset appends charset to text/* content types served by the
+// handler that do not already specify one. Other types are left untouched.
+func WithDefaultCharset(charset string) Option {
+	return func(filer *Httpfs) {
+		filer.charset = charset
+	}
+}
+
+// addCharset adds the default charset to the Content-Type in h if it is a
+// text type lacking one.
+func (filer *Httpfs) addCharset(code int, h http.Header) {
+	ctype := h.Get("Content-Type")
+	mediatype, params, err := mime.ParseMediaType(ctype)
+	if err != nil || !strings.HasPrefix(mediatype, "text/") || params["charset"] != "" {
+		return
+	}
+	h.Set("Content-Type", ctype+"; charset="+filer.charset)
+}
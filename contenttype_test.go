@@ -0,0 +1,52 @@
+package httpfs_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestDefaultCharset(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/notes.txt": "h\xc3\xa9llo",
+		"/logo.png":  "\x89PNG\r\n\x1a\n",
+	}), httpfs.WithDefaultCharset("utf-8"))
+
+	tests := map[string]string{
+		"/notes.txt": "text/plain; charset=utf-8",
+		"/logo.png":  "image/png",
+	}
+	for name, want := range tests {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", name, nil))
+		if ct := w.Header().Get("Content-Type"); ct != want {
+			t.Errorf("%s: Content-Type = %q, want %q", name, ct, want)
+		}
+	}
+}
+
+func TestContentTypeFunc(t *testing.T) {
+	manifest := map[string]string{"/blobs/3f2a": "application/wasm"}
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/blobs/3f2a": "\x00asm\x01\x00\x00\x00",
+		"/blobs/9c1d": "plain text",
+		"/page.html":  "<p>hi</p>",
+	}), httpfs.WithContentTypeFunc(func(name string, info os.FileInfo) string {
+		return manifest[name]
+	}))
+
+	tests := map[string]string{
+		"/blobs/3f2a": "application/wasm",
+		"/blobs/9c1d": "text/plain; charset=utf-8",
+		"/page.html":  "text/html; charset=utf-8",
+	}
+	for name, want := range tests {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", name, nil))
+		if ct := w.Header().Get("Content-Type"); ct != want {
+			t.Errorf("%s: Content-Type = %q, want %q", name, ct, want)
+		}
+	}
+}
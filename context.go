@@ -0,0 +1,159 @@
+package httpfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// OpenContext opens the named file for reading as Open does, tied to ctx:
+// once ctx is done, reads from the file fail with ctx.Err(). Files served
+// over HTTP are opened with the request's context, so that a client going
+// away stops reads from a slow filer. Files read from the cache set with
+// WithCache are not tied to ctx.
+func (filer *Httpfs) OpenContext(ctx context.Context, name string) (http.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	f, err := filer.openContext(ctx, name)
+	if os.IsNotExist(err) && filer.cleanURLs {
+		f, name, err = filer.openClean(ctx, name, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := filer.checkListing(name, f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if filer.readRate <= 0 {
+		return f, nil
+	}
+	return &throttledFile{File: f, ctx: ctx, bucket: newTokenBucket(filer.readRate)}, nil
+}
+
+// WithCleanURLs makes Open, and so the handler, resolve names without an
+// extension that do not exist, such as /about, to the page name.html if it
+// exists, and otherwise to name/index.html, as static site hosts do. Names
+// that exist are never rewritten. On filers that report directories, name
+// exists whenever name/index.html does, so the second form only applies to
+// filers without directories, such as object stores; elsewhere a directory
+// is redirected to name/ and its index page served as without clean URLs.
+func WithCleanURLs(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.cleanURLs = enabled
+	}
+}
+
+// openClean opens the page a clean URL name resolves to, returning it and
+// its name. If there is none it returns err, the error opening name. The
+// index page can only be found here if opening its directory failed, as it
+// does on filers without directories.
+func (filer *Httpfs) openClean(ctx context.Context, name string, err error) (http.File, string, error) {
+	clean := path.Clean("/" + name)
+	if clean == "/" || strings.HasSuffix(name, "/") || path.Ext(clean) != "" {
+		return nil, name, err
+	}
+	for _, page := range []string{clean + ".html", path.Join(clean, indexPage)} {
+		f, perr := filer.openContext(ctx, page)
+		if perr == nil {
+			return f, page, nil
+		}
+	}
+	return nil, name, err
+}
+
+// openContext opens the named file as OpenContext does, without pacing reads.
+func (filer *Httpfs) openContext(ctx context.Context, name string) (http.File, error) {
+	if filer.cache != nil {
+		f, err := filer.openCached(name)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			return f, nil
+		}
+	}
+	f, err := filer.openFileContext(ctx, name, os.O_RDONLY, 0400)
+	if err != nil {
+		return nil, err
+	}
+	return &httpFile{File: f, ctx: ctx, filer: filer, name: name}, nil
+}
+
+// httpFile is a file opened by OpenContext. Its reads fail once ctx is done,
+// and its Stat reports the modtime from the filer's Stat if the file's own
+// Stat reports none, so that conditional requests work. Reads from a
+// directory fail with syscall.EISDIR, as they do from an os.File, whatever
+// the filer would do.
+type httpFile struct {
+	absfs.File
+	ctx   context.Context
+	filer *Httpfs
+	name  string
+
+	// checked is set once isDir is known, on the first Read.
+	checked, isDir bool
+}
+
+func (f *httpFile) Read(p []byte) (int, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if !f.checked {
+		info, err := f.File.Stat()
+		f.checked, f.isDir = true, err == nil && info.IsDir()
+	}
+	if f.isDir {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EISDIR}
+	}
+	return f.File.Read(p)
+}
+
+// Seek seeks as the file does, but computes offsets relative to the end
+// itself, from the size reported by Stat, as filers do not all get those
+// right and http.ServeContent depends on them to serve ranges.
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart, io.SeekCurrent:
+	case io.SeekEnd:
+		info, err := f.File.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += info.Size()
+		whence = io.SeekStart
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	if whence == io.SeekStart && offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *httpFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil || !info.ModTime().IsZero() {
+		return info, err
+	}
+	if finfo, err := f.filer.Stat(f.name); err == nil && !finfo.ModTime().IsZero() {
+		return modTimeInfo{FileInfo: info, modTime: finfo.ModTime()}, nil
+	}
+	return info, nil
+}
+
+// modTimeInfo overrides the modtime of a FileInfo.
+type modTimeInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (info modTimeInfo) ModTime() time.Time { return info.modTime }
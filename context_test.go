@@ -0,0 +1,280 @@
+package httpfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+func TestOpenContext(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/file.txt": "hello world"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := fs.OpenContext(ctx, "/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 5)
+	if n, err := f.Read(buf); err != nil || string(buf[:n]) != "hello" {
+		t.Fatalf("Read = %q, %v", buf[:n], err)
+	}
+	cancel()
+	if n, err := f.Read(buf); n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("Read after cancel = %d, %v; want 0, %v", n, err, context.Canceled)
+	}
+
+	if _, err := fs.OpenContext(ctx, "/file.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("OpenContext with a done context: err = %v", err)
+	}
+
+	f, err = fs.Open("/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if data, err := ioutil.ReadAll(f); err != nil || string(data) != "hello world" {
+		t.Errorf("Open read %q, %v", data, err)
+	}
+}
+
+func TestServeCanceledRequest(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/file.txt": "hello world"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/file.txt", nil).WithContext(ctx))
+	if w.Code == http.StatusOK && w.Body.Len() != 0 {
+		t.Errorf("served %q for a canceled request", w.Body.String())
+	}
+}
+
+// zeroModTimeFS opens files whose Stat reports a zero modtime, while the
+// filer's Stat reports the real one.
+type zeroModTimeFS struct {
+	absfs.Filer
+}
+
+func (fs *zeroModTimeFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &zeroModTimeFile{f}, nil
+}
+
+type zeroModTimeFile struct {
+	absfs.File
+}
+
+func (f *zeroModTimeFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: info.Name(), size: info.Size(), mode: info.Mode()}, nil
+}
+
+func TestOpenModTimeFallback(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{"/file.txt": "hello"})
+	mtime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := mfs.Chtimes("/file.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fs := httpfs.New(&zeroModTimeFS{mfs})
+
+	f, err := fs.Open("/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("ModTime = %v, want %v", info.ModTime(), mtime)
+	}
+	if info.Size() != 5 || info.Name() != "file.txt" {
+		t.Errorf("info = %v", info)
+	}
+
+	req := httptest.NewRequest("GET", "/file.txt", nil)
+	req.Header.Set("If-Modified-Since", mtime.Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+}
+
+func TestOpenDirectory(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/dir/a.txt": "a"}))
+
+	f, err := fs.Open("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := f.Read(make([]byte, 16))
+	if n != 0 || err == nil || err == io.EOF || !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("Read = %d, %v; want EISDIR", n, err)
+	}
+	if info, err := f.Stat(); err != nil || !info.IsDir() {
+		t.Errorf("Stat = %v, %v", info, err)
+	}
+	if infos, err := f.Readdir(0); err != nil || len(infos) != 1 {
+		t.Errorf("Readdir = %v, %v", infos, err)
+	}
+}
+
+// endSeekFS opens files whose Seek takes offsets relative to the end as
+// relative to the start.
+type endSeekFS struct {
+	absfs.Filer
+}
+
+func (fs *endSeekFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return endSeekFile{f}, nil
+}
+
+type endSeekFile struct {
+	absfs.File
+}
+
+func (f endSeekFile) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		whence = io.SeekStart
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func TestMultipleRanges(t *testing.T) {
+	fs := httpfs.New(&endSeekFS{newMemFS(t, map[string]string{"/file.txt": "0123456789"})})
+
+	f, err := fs.Open("/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos, err := f.Seek(-3, io.SeekEnd); err != nil || pos != 7 {
+		t.Errorf("Seek(-3, SeekEnd) = %d, %v, want 7", pos, err)
+	}
+	if pos, err := f.Seek(1, io.SeekCurrent); err != nil || pos != 8 {
+		t.Errorf("Seek(1, SeekCurrent) = %d, %v, want 8", pos, err)
+	}
+	if _, err := f.Seek(-11, io.SeekEnd); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("Seek before the start: err = %v", err)
+	}
+	f.Close()
+
+	req := httptest.NewRequest("GET", "/file.txt", nil)
+	req.Header.Set("Range", "bytes=0-1,-3")
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for _, want := range []struct{ rng, data string }{
+		{"bytes 0-1/10", "01"},
+		{"bytes 7-9/10", "789"},
+	} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(part)
+		if cr := part.Header.Get("Content-Range"); cr != want.rng || string(data) != want.data {
+			t.Errorf("part %q %q, want %q %q", cr, data, want.rng, want.data)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("extra part: %v", err)
+	}
+}
+
+// flatFS presents no directories, as object stores do: opening one fails as
+// if it did not exist.
+type flatFS struct {
+	absfs.Filer
+}
+
+func (fs *flatFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if info, err := fs.Filer.Stat(name); err == nil && info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func TestCleanURLs(t *testing.T) {
+	fs := httpfs.New(&flatFS{newMemFS(t, map[string]string{
+		"/about.html":      "about page",
+		"/docs/index.html": "docs index",
+		"/blog/post":       "no extension",
+		"/blog.html":       "blog page",
+	})}, httpfs.WithCleanURLs(true))
+
+	get := func(fs *httpfs.Httpfs, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	for target, want := range map[string]string{
+		"/about":     "about page",
+		"/docs":      "docs index",
+		"/blog/post": "no extension",
+	} {
+		w := get(fs, target)
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want %q", target, w.Code, w.Body, want)
+		}
+	}
+	if ct := get(fs, "/about").Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("GET /about: Content-Type = %q", ct)
+	}
+	for _, target := range []string{"/missing", "/about.txt"} {
+		if w := get(fs, target); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", target, w.Code)
+		}
+	}
+	if _, err := fs.Open("/about/"); !os.IsNotExist(err) {
+		t.Errorf("Open(/about/) = %v, want not exist", err)
+	}
+
+	fs = httpfs.New(newMemFS(t, map[string]string{
+		"/team/a.txt": "a",
+		"/team.html":  "team page",
+	}), httpfs.WithCleanURLs(true))
+	if w := get(fs, "/team"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "team/" {
+		t.Errorf("existing directory rewritten: GET /team = %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	_, err := httpfs.New(newMemFS(t, map[string]string{"/about.html": ""})).Open("/about")
+	if !os.IsNotExist(err) {
+		t.Errorf("Open(/about) without WithCleanURLs: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package httpfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// Copy copies the file src to dst, creating or truncating dst, or copies the
+// directory src recursively, creating dst and the directories in it as
+// needed. Copies keep the permissions of the originals. Copying a file or
+// directory onto itself, or a directory onto an existing file or into
+// itself, fails.
+func (filer *Httpfs) Copy(src, dst string) error {
+	src, dst = path.Clean("/"+src), path.Clean("/"+dst)
+	info, err := filer.Stat(src)
+	if err != nil {
+		return err
+	}
+	if src == dst || info.IsDir() && strings.HasPrefix(dst, dirPath(src)) {
+		return &os.LinkError{Op: "copy", Old: src, New: dst, Err: syscall.EINVAL}
+	}
+	return filer.copyTree(src, dst, info)
+}
+
+// copyTree copies the file or directory src, whose info is given, to dst,
+// preserving permissions. Directories are copied recursively.
+func (filer *Httpfs) copyTree(src, dst string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return filer.copyFile(src, dst, info.Mode().Perm())
+	}
+
+	err := filer.Mkdir(dst, info.Mode().Perm())
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	infos, err := filer.readDirInfos(src)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		err = filer.copyTree(path.Join(src, info.Name()), path.Join(dst, info.Name()), info)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the contents of the file src to dst, creating or
+// truncating dst, and gives dst the permissions perm.
+func (filer *Httpfs) copyFile(src, dst string, perm os.FileMode) error {
+	in, err := filer.openFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := filer.openFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return filer.Chmod(dst, perm)
+}
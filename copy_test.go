@@ -0,0 +1,72 @@
+package httpfs_test
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestCopy(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/file.txt":       "file",
+		"/existing.txt":   "a much longer existing file",
+		"/dir/a.txt":      "a",
+		"/dir/sub/b.txt":  "b",
+		"/dir/sub/deep/c": "c",
+	}))
+	if err := fs.Chmod("/file.txt", 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Copy("/file.txt", "/copy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Copy("/file.txt", "/existing.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"/file.txt", "/copy.txt", "/existing.txt"} {
+		if got := readFile(t, fs, name); got != "file" {
+			t.Errorf("%s = %q, want %q", name, got, "file")
+		}
+		info, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s: mode = %v, want %v", name, perm, os.FileMode(0600))
+		}
+	}
+
+	if err := fs.Copy("/dir", "/dupe"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"/dupe/a.txt":      "a",
+		"/dupe/sub/b.txt":  "b",
+		"/dupe/sub/deep/c": "c",
+		"/dir/sub/b.txt":   "b",
+	} {
+		if got := readFile(t, fs, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if err := fs.Copy("/dir", "/file.txt"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("directory onto file: err = %v, want %v", err, syscall.ENOTDIR)
+	}
+	if err := fs.Copy("/dir", "/dir/sub/again"); err == nil {
+		t.Error("copying a directory into itself succeeded")
+	}
+	if err := fs.Copy("/file.txt", "/./file.txt"); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("file onto itself: err = %v, want %v", err, syscall.EINVAL)
+	}
+	if got := readFile(t, fs, "/file.txt"); got != "file" {
+		t.Errorf("file copied onto itself = %q", got)
+	}
+	if err := fs.Copy("/missing", "/x"); !os.IsNotExist(err) {
+		t.Errorf("missing source: err = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package httpfs
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// CountingFiler wraps an absfs.Filer, counting the calls made to each of its
+// methods, to check in tests that a cache spares the filer, or to find out
+// what a workload costs. Only the methods of absfs.Filer are wrapped, so a
+// CountingFiler hides the optional methods of the filer, such as Rename.
+type CountingFiler struct {
+	absfs.Filer
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewCountingFiler returns a CountingFiler wrapping fs with all counts zero.
+func NewCountingFiler(fs absfs.Filer) *CountingFiler {
+	return &CountingFiler{Filer: fs, counts: make(map[string]int)}
+}
+
+// Counts returns the number of calls made to each method so far, keyed by
+// method name, such as "OpenFile" or "Stat". Methods never called are left
+// out.
+func (c *CountingFiler) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int, len(c.counts))
+	for method, n := range c.counts {
+		counts[method] = n
+	}
+	return counts
+}
+
+func (c *CountingFiler) count(method string) {
+	c.mu.Lock()
+	c.counts[method]++
+	c.mu.Unlock()
+}
+
+func (c *CountingFiler) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	c.count("OpenFile")
+	return c.Filer.OpenFile(name, flag, perm)
+}
+
+func (c *CountingFiler) Mkdir(name string, perm os.FileMode) error {
+	c.count("Mkdir")
+	return c.Filer.Mkdir(name, perm)
+}
+
+func (c *CountingFiler) Remove(name string) error {
+	c.count("Remove")
+	return c.Filer.Remove(name)
+}
+
+func (c *CountingFiler) Stat(name string) (os.FileInfo, error) {
+	c.count("Stat")
+	return c.Filer.Stat(name)
+}
+
+func (c *CountingFiler) Chmod(name string, mode os.FileMode) error {
+	c.count("Chmod")
+	return c.Filer.Chmod(name, mode)
+}
+
+func (c *CountingFiler) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	c.count("Chtimes")
+	return c.Filer.Chtimes(name, atime, mtime)
+}
+
+func (c *CountingFiler) Chown(name string, uid, gid int) error {
+	c.count("Chown")
+	return c.Filer.Chown(name, uid, gid)
+}
@@ -0,0 +1,49 @@
+package httpfs_test
+
+import (
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestCountingFiler(t *testing.T) {
+	c := httpfs.NewCountingFiler(newMemFS(t, map[string]string{
+		"/a.txt": "a",
+		"/b.txt": "b",
+	}))
+	fs := httpfs.New(c)
+
+	if got := c.Counts(); len(got) != 0 {
+		t.Errorf("initial counts = %v", got)
+	}
+	for _, name := range []string{"/a.txt", "/b.txt"} {
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if _, err := fs.Stat("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := c.Counts()
+	if got["OpenFile"] != 2 || got["Stat"] < 1 || got["Remove"] != 1 {
+		t.Errorf("counts = %v", got)
+	}
+
+	// Counts returns a copy
+	got["Remove"] = 10
+	if n := c.Counts()["Remove"]; n != 1 {
+		t.Errorf("Remove count = %d after changing the copy", n)
+	}
+	if err := fs.Remove("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Counts()["Remove"]; n != 2 {
+		t.Errorf("Remove count = %d, want 2", n)
+	}
+}
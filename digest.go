@@ -0,0 +1,121 @@
+package httpfs
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// digestMaxSize is the largest file whose digest is computed while serving
+// it. Larger files only get a Digest header once their digest is cached.
+const digestMaxSize = 1 << 20
+
+// digestCacheSize bounds the number of cached digests.
+const digestCacheSize = 4096
+
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// WithResponseDigest adds a Digest header, as in "sha-256=<base64>", to served
+// files. The supported algorithms are "sha-256" and "sha-512"; any other
+// value disables the header. Digests are cached by path, size and modtime so
+// files are not read twice, and are computed while serving only for files up
+// to 1 MiB.
+func WithResponseDigest(algo string) Option {
+	return func(filer *Httpfs) {
+		algo = strings.ToLower(algo)
+		newHash, ok := digestAlgorithms[algo]
+		if !ok {
+			filer.digest = nil
+			return
+		}
+		filer.digest = newDigester(algo, newHash, digestMaxSize)
+	}
+}
+
+// fileKey identifies a version of a file by its path, size and modtime.
+type fileKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func newFileKey(name string, info os.FileInfo) fileKey {
+	return fileKey{name: name, size: info.Size(), modTime: info.ModTime()}
+}
+
+// digester computes and caches digests of served files.
+type digester struct {
+	algo    string
+	newHash func() hash.Hash
+	maxSize int64
+
+	mu    sync.Mutex
+	cache map[fileKey][]byte
+}
+
+func newDigester(algo string, newHash func() hash.Hash, maxSize int64) *digester {
+	return &digester{algo: algo, newHash: newHash, maxSize: maxSize, cache: make(map[fileKey][]byte)}
+}
+
+// setHeader sets the Digest header for the file name, open as f, if its
+// digest is cached or the file is small enough to compute it.
+func (d *digester) setHeader(h http.Header, name string, info os.FileInfo, f io.ReadSeeker) {
+	if sum, ok := d.sum(name, info, f); ok {
+		h.Set("Digest", d.algo+"="+base64.StdEncoding.EncodeToString(sum))
+	}
+}
+
+// sum returns the digest of the file name, open as f, if it is cached or the
+// file is no larger than maxSize so that it can be computed.
+func (d *digester) sum(name string, info os.FileInfo, f io.ReadSeeker) ([]byte, bool) {
+	key := newFileKey(name, info)
+	d.mu.Lock()
+	sum, ok := d.cache[key]
+	d.mu.Unlock()
+	if ok {
+		return sum, true
+	}
+
+	if info.Size() > d.maxSize {
+		return nil, false
+	}
+	sum, err := d.compute(f)
+	if err != nil {
+		return nil, false
+	}
+	d.mu.Lock()
+	if len(d.cache) >= digestCacheSize {
+		d.cache = make(map[fileKey][]byte)
+	}
+	d.cache[key] = sum
+	d.mu.Unlock()
+	return sum, true
+}
+
+// compute returns the digest of f, leaving f at its start.
+func (d *digester) compute(f io.ReadSeeker) ([]byte, error) {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	hash := d.newHash()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,35 @@
+package httpfs_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestResponseDigest(t *testing.T) {
+	data := "digest me"
+	fs := httpfs.New(newMemFS(t, map[string]string{"/file.txt": data}), httpfs.WithResponseDigest("SHA-256"))
+
+	sum := sha256.Sum256([]byte(data))
+	want := "sha-256=" + base64.StdEncoding.EncodeToString(sum[:])
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/file.txt", nil))
+		if w.Code != http.StatusOK || w.Body.String() != data {
+			t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Digest"); got != want {
+			t.Errorf("Digest = %q, want %q", got, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	httpfs.New(newMemFS(t, map[string]string{"/file.txt": data})).ServeHTTP(w, httptest.NewRequest("GET", "/file.txt", nil))
+	if got := w.Header().Get("Digest"); got != "" {
+		t.Errorf("Digest = %q without WithResponseDigest", got)
+	}
+}
@@ -0,0 +1,79 @@
+package httpfs
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/absfs/absfs"
+)
+
+// WithHideDotfiles makes files and directories whose names begin with a dot
+// invisible: opening or stating a path with such a component fails with
+// fs.ErrNotExist, and they are left out of ReadDir, of Readdir on opened
+// directories and of directory listings.
+func WithHideDotfiles(hide bool) Option {
+	return func(filer *Httpfs) {
+		filer.hideDotfiles = hide
+	}
+}
+
+// hidden reports whether name is hidden by WithHideDotfiles.
+func (filer *Httpfs) hidden(name string) bool {
+	if !filer.hideDotfiles {
+		return false
+	}
+	for _, elem := range strings.Split(path.Clean("/"+filer.slashPath(name)), "/") {
+		if strings.HasPrefix(elem, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// listDir returns the entries of the directory name sorted by filename,
+// leaving out dotfiles if they are hidden.
+func (filer *Httpfs) listDir(name string) ([]os.FileInfo, error) {
+	infos, err := filer.readDirInfos(name)
+	if err != nil || !filer.hideDotfiles {
+		return infos, err
+	}
+	return withoutDotfiles(infos), nil
+}
+
+// withoutDotfiles filters the entries whose names begin with a dot out of
+// infos in place.
+func withoutDotfiles(infos []os.FileInfo) []os.FileInfo {
+	n := 0
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") {
+			infos[n] = info
+			n++
+		}
+	}
+	return infos[:n]
+}
+
+// dotfileFilter leaves dotfiles out of the entries read from a directory.
+type dotfileFilter struct {
+	absfs.File
+}
+
+func (f *dotfileFilter) Readdir(n int) ([]os.FileInfo, error) {
+	for {
+		infos, err := f.File.Readdir(n)
+		infos = withoutDotfiles(infos)
+		if len(infos) > 0 || err != nil || n <= 0 {
+			return infos, err
+		}
+	}
+}
+
+func (f *dotfileFilter) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
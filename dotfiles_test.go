@@ -0,0 +1,58 @@
+package httpfs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestHideDotfiles(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{
+		"/dir/.env":      "secret",
+		"/dir/.git/HEAD": "ref",
+		"/dir/app.js":    "app",
+	})
+	fs := httpfs.New(mfs, httpfs.WithHideDotfiles(true))
+
+	for _, name := range []string{"/dir/.env", "/dir/.git", "/dir/.git/HEAD", "/dir/./.env"} {
+		if _, err := fs.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): err = %v, want not exist", name, err)
+		}
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Stat(%q): err = %v, want not exist", name, err)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", name, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d", name, w.Code)
+		}
+	}
+
+	entries, err := fs.ReadDir("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "app.js" {
+		t.Errorf("ReadDir = %v", entries)
+	}
+
+	f, err := fs.Open("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := f.Readdir(1)
+	if err != nil || len(infos) != 1 || infos[0].Name() != "app.js" {
+		t.Errorf("Readdir(1) = %v, %v", infos, err)
+	}
+	f.Close()
+
+	if err := fs.RemoveAll("/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfs.Stat("/dir"); !os.IsNotExist(err) {
+		t.Errorf("RemoveAll left the directory behind: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package httpfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"math"
+	"os"
+	"strconv"
+)
+
+// WithETag sets a weak ETag of the form W/"<size>-<modtime>" on served files,
+// with the modtime in nanoseconds since the Unix epoch, so that conditional
+// requests can be answered with 304 Not Modified. The ETag depends only on
+// file metadata and so is stable across restarts. A validator set with
+// WithValidator takes precedence.
+func WithETag(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.etag = enabled
+	}
+}
+
+// weakETag returns the ETag set by WithETag for the file info.
+func weakETag(info os.FileInfo) string {
+	buf := make([]byte, 0, 48)
+	buf = append(buf, `W/"`...)
+	buf = strconv.AppendInt(buf, info.Size(), 10)
+	buf = append(buf, '-')
+	buf = strconv.AppendInt(buf, info.ModTime().UnixNano(), 10)
+	buf = append(buf, '"')
+	return string(buf)
+}
+
+// WithStrongETag sets a strong ETag of the form "<hex>" on served files, the
+// hex encoded hash of their contents computed with a hash from newHash, such
+// as sha256.New. Unlike the ETag set by WithETag it tells apart contents of
+// the same size. A file is hashed the first time it is served, and the hash
+// is reused until its size or modtime changes, so an edit that keeps both,
+// within the resolution of the modtime, is not seen. Responses compressed on
+// the fly carry the ETag weakened. It takes precedence over WithETag, and a
+// validator set with WithValidator takes precedence over it.
+func WithStrongETag(newHash func() hash.Hash) Option {
+	return func(filer *Httpfs) {
+		filer.strongETag = nil
+		if newHash != nil {
+			filer.strongETag = newDigester("", newHash, math.MaxInt64)
+		}
+	}
+}
+
+// DirETag returns an ETag derived from the names, sizes and modtimes of the
+// immediate entries of dir. Adding, removing or modifying an entry changes
+// the ETag, while an unchanged directory always yields the same value, even
+// on filers whose directory modtimes are unreliable.
+func (filer *Httpfs) DirETag(dir string) (string, error) {
+	infos, err := filer.listDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	var buf []byte
+	for _, info := range infos {
+		buf = append(buf[:0], info.Name()...)
+		buf = append(buf, 0)
+		buf = strconv.AppendInt(buf, info.Size(), 10)
+		buf = append(buf, 0)
+		buf = strconv.AppendInt(buf, info.ModTime().UnixNano(), 10)
+		buf = append(buf, '\n')
+		h.Write(buf)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
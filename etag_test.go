@@ -0,0 +1,175 @@
+package httpfs_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestDirETag(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/dir/a.txt": "a",
+		"/dir/b.txt": "b",
+	}))
+
+	etag := func() string {
+		t.Helper()
+		tag, err := fs.DirETag("/dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag
+	}
+
+	base := etag()
+	if again := etag(); again != base {
+		t.Fatalf("unchanged directory: ETag %s != %s", again, base)
+	}
+
+	seen := map[string]string{base: "initial"}
+	changed := func(what string) {
+		t.Helper()
+		tag := etag()
+		if prev, ok := seen[tag]; ok {
+			t.Errorf("%s: ETag %s same as after %s", what, tag, prev)
+		}
+		seen[tag] = what
+	}
+
+	f, err := fs.OpenFile("/dir/c.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	changed("add")
+
+	mtime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	err = fs.Chtimes("/dir/a.txt", mtime, mtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed("modify")
+
+	err = fs.Remove("/dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed("remove")
+
+	if _, err := fs.DirETag("/dir/a.txt"); err == nil {
+		t.Error("DirETag of a file succeeded")
+	}
+}
+
+func TestWithETag(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{"/file.txt": "hello"})
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	if err := mfs.Chtimes("/file.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fs := httpfs.New(mfs, httpfs.WithETag(true))
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/file.txt", nil))
+	want := `W/"5-` + strconv.FormatInt(mtime.UnixNano(), 10) + `"`
+	if etag := w.Header().Get("ETag"); etag != want {
+		t.Fatalf("ETag = %q, want %q", etag, want)
+	}
+
+	req := httptest.NewRequest("GET", "/file.txt", nil)
+	req.Header.Set("If-None-Match", want)
+	w = httptest.NewRecorder()
+	fs.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	w = httptest.NewRecorder()
+	httpfs.New(mfs).ServeHTTP(w, httptest.NewRequest("GET", "/file.txt", nil))
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("ETag = %q without WithETag", etag)
+	}
+}
+
+func TestWithStrongETag(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a.txt": "aaaa"}), httpfs.WithStrongETag(sha256.New))
+
+	get := func(inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/a.txt", nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, r)
+		return w
+	}
+
+	etag := get("").Header().Get("ETag")
+	sum := sha256.Sum256([]byte("aaaa"))
+	if want := `"` + hex.EncodeToString(sum[:]) + `"`; etag != want {
+		t.Fatalf("ETag = %q, want %q", etag, want)
+	}
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match with the ETag: status %d", w.Code)
+	}
+
+	f, err := fs.OpenFile("/a.txt", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("bbbb"))
+	f.Close()
+	mtime := time.Now().Add(time.Hour)
+	if err := fs.Chtimes("/a.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	w := get(etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("If-None-Match with the old ETag: status %d", w.Code)
+	}
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Errorf("ETag unchanged after a same-size edit: %q", got)
+	}
+}
+
+func TestStrongETagCompressed(t *testing.T) {
+	text := strings.Repeat("compressible text ", 100)
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a.txt": text}),
+		httpfs.WithStrongETag(sha256.New), httpfs.WithResponseCompression(256))
+
+	get := func(encoding, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/a.txt", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, r)
+		return w
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	strong := `"` + hex.EncodeToString(sum[:]) + `"`
+	if etag := get("", "").Header().Get("ETag"); etag != strong {
+		t.Errorf("identity ETag = %q, want %q", etag, strong)
+	}
+	w := get("gzip", "")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("response not compressed: %v", w.Header())
+	}
+	if etag := w.Header().Get("ETag"); etag != "W/"+strong {
+		t.Errorf("gzip ETag = %q, want %q", etag, "W/"+strong)
+	}
+	if w := get("gzip", "W/"+strong); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match with the weakened ETag: status %d", w.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package httpfs
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+)
+
+// Exists reports whether the named file or directory exists. Errors other
+// than not-exist are returned.
+func (filer *Httpfs) Exists(name string) (bool, error) {
+	_, err := filer.Stat(name)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+// IsDir reports whether name exists and is a directory. Errors other than
+// not-exist are returned.
+func (filer *Httpfs) IsDir(name string) (bool, error) {
+	info, err := filer.Stat(name)
+	switch {
+	case err == nil:
+		return info.IsDir(), nil
+	case os.IsNotExist(err):
+		return false, nil
+	}
+	return false, err
+}
+
+// existsWorkers bounds the number of concurrent Stat calls made by ExistsMany.
+const existsWorkers = 8
+
+// ExistsMany reports which of paths exist, stating them concurrently with a
+// bounded number of workers. Paths that do not exist map to false. Any other
+// error aborts the check and is returned.
+func (filer *Httpfs) ExistsMany(paths ...string) (map[string]bool, error) {
+	var (
+		exists   = make(map[string]bool, len(paths))
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		work     = make(chan string)
+		abort    = make(chan struct{})
+	)
+
+	workers := existsWorkers
+	if len(paths) < workers {
+		workers = len(paths)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				_, err := filer.Stat(name)
+				mu.Lock()
+				switch {
+				case err == nil:
+					exists[name] = true
+				case os.IsNotExist(err):
+					exists[name] = false
+				case firstErr == nil:
+					firstErr = err
+					close(abort)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, name := range paths {
+		select {
+		case work <- name:
+		case <-abort:
+			break send
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return exists, nil
+}
+
+// NearestExisting returns the deepest of name and its ancestors that exists,
+// along with its FileInfo. Errors other than not-exist are returned.
+func (filer *Httpfs) NearestExisting(name string) (string, os.FileInfo, error) {
+	name = path.Clean("/" + name)
+	for {
+		info, err := filer.Stat(name)
+		if err == nil {
+			return name, info, nil
+		}
+		if (!os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR)) || name == "/" {
+			return "", nil, err
+		}
+		name = path.Dir(name)
+	}
+}
@@ -0,0 +1,131 @@
+package httpfs_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// slowStatFS delays every Stat, records the peak number of concurrent calls
+// and fails with errs[name] for the given names.
+type slowStatFS struct {
+	absfs.Filer
+	delay    time.Duration
+	errs     map[string]error
+	inflight int32
+	peak     int32
+}
+
+func (fs *slowStatFS) Stat(name string) (os.FileInfo, error) {
+	n := atomic.AddInt32(&fs.inflight, 1)
+	defer atomic.AddInt32(&fs.inflight, -1)
+	for {
+		peak := atomic.LoadInt32(&fs.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&fs.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(fs.delay)
+	if err, ok := fs.errs[name]; ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fs.Filer.Stat(name)
+}
+
+func TestExistsAndIsDir(t *testing.T) {
+	errDenied := errors.New("denied")
+	fs := httpfs.New(&slowStatFS{
+		Filer: newMemFS(t, map[string]string{"/dir/a.txt": "a"}),
+		errs:  map[string]error{"/secret": errDenied},
+	})
+
+	tests := []struct {
+		name          string
+		exists, isDir bool
+		err           error
+	}{
+		{"/dir", true, true, nil},
+		{"/dir/a.txt", true, false, nil},
+		{"/missing", false, false, nil},
+		{"/secret", false, false, errDenied},
+	}
+	for _, tt := range tests {
+		exists, err := fs.Exists(tt.name)
+		if exists != tt.exists || !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Errorf("Exists(%q) = %v, %v; want %v, %v", tt.name, exists, err, tt.exists, tt.err)
+		}
+		isDir, err := fs.IsDir(tt.name)
+		if isDir != tt.isDir || !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Errorf("IsDir(%q) = %v, %v; want %v, %v", tt.name, isDir, err, tt.isDir, tt.err)
+		}
+	}
+}
+
+func TestExistsMany(t *testing.T) {
+	sfs := &slowStatFS{
+		Filer: newMemFS(t, map[string]string{
+			"/a.txt":     "a",
+			"/dir/b.txt": "b",
+		}),
+		delay: time.Millisecond,
+	}
+	fs := httpfs.New(sfs)
+
+	paths := []string{"/a.txt", "/dir", "/dir/b.txt", "/missing.txt", "/dir/missing/c.txt"}
+	for i := 0; i < 60; i++ {
+		paths = append(paths, fmt.Sprintf("/gone%d", i))
+	}
+	exists, err := fs.ExistsMany(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exists) != len(paths) {
+		t.Fatalf("got %d results, want %d", len(exists), len(paths))
+	}
+	for _, name := range paths {
+		want := name == "/a.txt" || name == "/dir" || name == "/dir/b.txt"
+		if exists[name] != want {
+			t.Errorf("exists[%q] = %v, want %v", name, exists[name], want)
+		}
+	}
+	if sfs.peak < 2 || int(sfs.peak) >= len(paths) {
+		t.Errorf("peak concurrency %d not bounded", sfs.peak)
+	}
+
+	denied := errors.New("permission denied")
+	sfs.errs = map[string]error{"/dir/b.txt": denied}
+	exists, err = fs.ExistsMany(paths...)
+	if err == nil || !errors.Is(err, denied) {
+		t.Fatalf("err = %v, want %v", err, denied)
+	}
+	if exists != nil {
+		t.Errorf("exists = %v, want nil on error", exists)
+	}
+}
+
+func TestNearestExisting(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a/b/file.txt": "x"}))
+
+	tests := map[string]string{
+		"/a/b/c/d/e.txt":     "/a/b",
+		"a/b/file.txt":       "/a/b/file.txt",
+		"/a/b/file.txt/nope": "/a/b/file.txt",
+		"/x/y":               "/",
+	}
+	for name, want := range tests {
+		got, info, err := fs.NearestExisting(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != want || info == nil {
+			t.Errorf("%s: NearestExisting = %q, %v, want %q", name, got, info, want)
+		}
+	}
+}
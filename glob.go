@@ -0,0 +1,76 @@
+package httpfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob returns the names of all files matching pattern, cleaned, absolute
+// and sorted. Patterns are matched one path element at a time with
+// path.Match, so they may use '*', '?' and character classes in any
+// element. The only possible error is path.ErrBadPattern, when pattern is
+// malformed; I/O errors reading directories are ignored.
+func (filer *Httpfs) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	matches, err := filer.glob(path.Clean("/" + pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (filer *Httpfs) glob(pattern string) ([]string, error) {
+	if !hasMeta(pattern) {
+		if _, err := filer.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := path.Split(pattern)
+	dir = path.Clean(dir)
+	if !hasMeta(dir) {
+		return filer.globDir(dir, file, nil)
+	}
+
+	dirs, err := filer.glob(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, d := range dirs {
+		matches, err = filer.globDir(d, file, matches)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// globDir appends to matches the names in dir matching pattern.
+func (filer *Httpfs) globDir(dir, pattern string, matches []string) ([]string, error) {
+	entries, err := filer.ReadDir(dir)
+	if err != nil {
+		return matches, nil
+	}
+	for _, e := range entries {
+		ok, err := path.Match(pattern, e.Name())
+		if err != nil {
+			return matches, err
+		}
+		if ok {
+			matches = append(matches, path.Join(dir, e.Name()))
+		}
+	}
+	return matches, nil
+}
+
+// hasMeta reports whether pattern contains any of the magic characters
+// recognized by path.Match.
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,55 @@
+package httpfs_test
+
+import (
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestGlob(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/assets/main.css":      "",
+		"/assets/print.css":     "",
+		"/assets/app.js":        "",
+		"/assets/v1/old.css":    "",
+		"/assets/v2/new.css":    "",
+		"/assets/v2/theme1.css": "",
+		"/assets/v2/theme2.css": "",
+		"/docs/readme.md":       "",
+	}))
+
+	for _, tc := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"/assets/*.css", []string{"/assets/main.css", "/assets/print.css"}},
+		{"assets/*.css", []string{"/assets/main.css", "/assets/print.css"}},
+		{"/assets/*/*.css", []string{"/assets/v1/old.css", "/assets/v2/new.css", "/assets/v2/theme1.css", "/assets/v2/theme2.css"}},
+		{"/assets/v?/theme[12].css", []string{"/assets/v2/theme1.css", "/assets/v2/theme2.css"}},
+		{"/*/readme.md", []string{"/docs/readme.md"}},
+		{"/assets/app.js", []string{"/assets/app.js"}},
+		{"/assets/[^m]*.css", []string{"/assets/print.css"}},
+	} {
+		got, err := fs.Glob(tc.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q): %v", tc.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Glob(%q) = %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+
+	for _, pattern := range []string{"/assets/*.png", "/missing/*", "/assets/nothing.css"} {
+		got, err := fs.Glob(pattern)
+		if err != nil || len(got) != 0 {
+			t.Errorf("Glob(%q) = %q, %v; want no matches", pattern, got, err)
+		}
+	}
+
+	if _, err := fs.Glob("/assets/[.css"); err != path.ErrBadPattern {
+		t.Errorf("bad pattern: err = %v, want %v", err, path.ErrBadPattern)
+	}
+}
@@ -0,0 +1,146 @@
+package httpfs
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/absfs/absfs"
+)
+
+// knownMethods are the HTTP methods the handler recognizes. Requests using
+// any other method are answered with 501 Not Implemented rather than 405
+// Method Not Allowed.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPut:     true,
+	http.MethodPost:    true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodPatch:   true,
+	"MKCOL":            true,
+}
+
+// ServeHTTP serves the filesystem over HTTP. GET and HEAD requests are
+// answered with file contents or directory listings, PUT requests write the
+// request body to the named file, DELETE requests remove the named file or
+// directory tree and MKCOL requests create a directory.
+func (filer *Httpfs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		filer.serve(w, r)
+	case r.Method == http.MethodPut:
+		filer.servePut(w, r)
+	case r.Method == http.MethodDelete:
+		filer.serveDelete(w, r)
+	case r.Method == "MKCOL":
+		filer.serveMkcol(w, r)
+	case !knownMethods[r.Method]:
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE, MKCOL")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// serveDelete removes the file or directory tree named by the request path.
+// The root cannot be deleted.
+func (filer *Httpfs) serveDelete(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	if name == "/" {
+		filer.serveStatus(w, r, http.StatusForbidden, nil)
+		return
+	}
+	if _, err := filer.Stat(name); err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	if err := filer.RemoveAll(name); err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveMkcol creates the directory named by the request path, answering as
+// WebDAV does with 405 if it already exists and 409 if its parent does not.
+func (filer *Httpfs) serveMkcol(w http.ResponseWriter, r *http.Request) {
+	err := filer.Mkdir(path.Clean("/"+r.URL.Path), 0755)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusCreated)
+	case os.IsExist(err), errors.Is(err, syscall.ENOTDIR):
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+	case os.IsNotExist(err):
+		http.Error(w, "409 Conflict", http.StatusConflict)
+	default:
+		filer.serveError(w, r, err)
+	}
+}
+
+// FileServer returns a handler serving the files of fs, configured with
+// opts, read-only: GET and HEAD requests are answered with file contents,
+// index.html pages or directory listings as ServeHTTP answers them, with
+// support for range and conditional requests, and any other method with 405
+// Method Not Allowed. Pass WithHideDotfiles to hide dotfiles.
+func FileServer(fs absfs.Filer, opts ...Option) http.Handler {
+	filer := New(fs, opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		filer.serve(w, r)
+	})
+}
+
+// AllowlistHandler returns a handler serving only the files named by paths
+// and answering any other request with 404 Not Found. Both the request path
+// and paths are cleaned before they are compared, case sensitively. The
+// handler is read-only: requests for listed paths using methods other than
+// GET and HEAD are answered with 405 Method Not Allowed.
+func (filer *Httpfs) AllowlistHandler(paths ...string) http.Handler {
+	allowed := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		allowed[path.Clean("/"+p)] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed[path.Clean("/"+r.URL.Path)] {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		filer.serve(w, r)
+	})
+}
+
+// headerWriter calls before with the status code and header just before the
+// header is written, giving it a last chance to adjust the header.
+type headerWriter struct {
+	http.ResponseWriter
+	before func(code int, h http.Header)
+	wrote  bool
+}
+
+func (w *headerWriter) WriteHeader(code int) {
+	if !w.wrote {
+		w.wrote = true
+		w.before(code, w.Header())
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
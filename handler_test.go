@@ -0,0 +1,143 @@
+package httpfs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestMethodNotImplemented(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/foo.txt": "foo"}))
+
+	tests := []struct {
+		method string
+		status int
+	}{
+		{"GET", http.StatusOK},
+		{"FROBNICATE", http.StatusNotImplemented},
+		{"PATCH", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest(test.method, "/foo.txt", nil))
+		if w.Code != test.status {
+			t.Errorf("%s: status = %d, want %d", test.method, w.Code, test.status)
+		}
+		if test.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") == "" {
+			t.Errorf("%s: missing Allow header", test.method)
+		}
+	}
+}
+
+func TestAllowlistHandler(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/docs/public.txt":  "public",
+		"/docs/private.txt": "private",
+	}))
+	h := fs.AllowlistHandler("docs/public.txt")
+
+	tests := map[string]int{
+		"/docs/public.txt":         http.StatusOK,
+		"/docs/../docs/public.txt": http.StatusOK,
+		"/docs/private.txt":        http.StatusNotFound,
+		"/docs/":                   http.StatusNotFound,
+		"/DOCS/public.txt":         http.StatusNotFound,
+	}
+	for url, status := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = url
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: status = %d, want %d", url, w.Code, status)
+		}
+		if status == http.StatusOK && w.Body.String() != "public" {
+			t.Errorf("%s: body = %q", url, w.Body.String())
+		}
+	}
+
+	for _, method := range []string{"PUT", "DELETE", "MKCOL", "POST"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/docs/public.txt", strings.NewReader("changed")))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: status = %d, want %d", method, w.Code, http.StatusMethodNotAllowed)
+		}
+	}
+	data, err := fs.ReadFile("/docs/public.txt")
+	if err != nil || string(data) != "public" {
+		t.Errorf("listed file after refused writes = %q, %v", data, err)
+	}
+}
+
+func TestReadWriteHandler(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/docs/old.txt": "old"}))
+
+	do := func(method, name, body string) int {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest(method, name, strings.NewReader(body)))
+		return w.Code
+	}
+
+	steps := []struct {
+		method, name, body string
+		status             int
+	}{
+		{"PUT", "/docs/new.txt", "new", http.StatusCreated},
+		{"PUT", "/docs/new.txt", "newer", http.StatusNoContent},
+		{"GET", "/docs/new.txt", "", http.StatusOK},
+		{"PUT", "/docs", "x", http.StatusConflict},
+		{"PUT", "/missing/new.txt", "x", http.StatusNotFound},
+		{"MKCOL", "/photos", "", http.StatusCreated},
+		{"MKCOL", "/photos", "", http.StatusMethodNotAllowed},
+		{"MKCOL", "/a/b", "", http.StatusConflict},
+		{"DELETE", "/docs", "", http.StatusNoContent},
+		{"DELETE", "/docs", "", http.StatusNotFound},
+		{"DELETE", "/", "", http.StatusForbidden},
+	}
+	for _, step := range steps {
+		if code := do(step.method, step.name, step.body); code != step.status {
+			t.Errorf("%s %s: status = %d, want %d", step.method, step.name, code, step.status)
+		}
+	}
+
+	if info, err := fs.Stat("/photos"); err != nil || !info.IsDir() {
+		t.Errorf("MKCOL did not create a directory: %v", err)
+	}
+	if _, err := fs.Stat("/docs/old.txt"); !os.IsNotExist(err) {
+		t.Errorf("DELETE left /docs/old.txt: %v", err)
+	}
+}
+
+func TestFileServerConstructor(t *testing.T) {
+	h := httpfs.FileServer(newMemFS(t, map[string]string{
+		"/docs/index.html": "index",
+		"/docs/a.txt":      "a",
+		"/.secret":         "secret",
+	}), httpfs.WithHideDotfiles(true))
+
+	get := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader("x")))
+		return w
+	}
+
+	if w := get("GET", "/docs/a.txt"); w.Code != http.StatusOK || w.Body.String() != "a" {
+		t.Errorf("GET a.txt = %d %q", w.Code, w.Body)
+	}
+	if w := get("GET", "/docs/"); w.Code != http.StatusOK || w.Body.String() != "index" {
+		t.Errorf("GET /docs/ = %d %q, want the index page", w.Code, w.Body)
+	}
+	if w := get("GET", "/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "docs/") || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("GET / = %d\n%s", w.Code, w.Body)
+	}
+	if w := get("GET", "/.secret"); w.Code != http.StatusNotFound {
+		t.Errorf("GET a dotfile: status %d", w.Code)
+	}
+	if w := get("PUT", "/docs/a.txt"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: status %d", w.Code)
+	}
+}
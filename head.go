@@ -0,0 +1,93 @@
+package httpfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// serveHead answers the HEAD request r for the file name from its FileInfo
+// alone, without opening it unless its content type must be sniffed, and
+// reports whether it did. It does not when name is a directory or does not
+// exist, or when the headers configured need the contents of the file, as
+// strong ETags, digests and precompressed sidecars do.
+func (filer *Httpfs) serveHead(w http.ResponseWriter, r *http.Request, name string) bool {
+	if r.Method != http.MethodHead || filer.strongETag != nil || filer.digest != nil || filer.sidecars {
+		return false
+	}
+	info, err := filer.Stat(name)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	f := &headFile{ctx: r.Context(), filer: filer, name: name, info: info}
+	defer f.Close()
+	filer.serveContent(w, r, name, info, f)
+	return true
+}
+
+// headFile is the http.File served in answer to a HEAD request. It seeks
+// within the size in its FileInfo and opens the file only when read from.
+type headFile struct {
+	ctx   context.Context
+	filer *Httpfs
+	name  string
+	info  os.FileInfo
+
+	pos int64
+	f   http.File
+}
+
+func (f *headFile) open() error {
+	if f.f != nil {
+		return nil
+	}
+	file, err := f.filer.OpenContext(f.ctx, f.name)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(f.pos, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	f.f = file
+	return nil
+}
+
+func (f *headFile) Read(p []byte) (int, error) {
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.f.Read(p)
+}
+
+func (f *headFile) Seek(offset int64, whence int) (int64, error) {
+	if f.f != nil {
+		return f.f.Seek(offset, whence)
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.info.Size()
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *headFile) Readdir(int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+}
+
+func (f *headFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *headFile) Close() error {
+	if f.f == nil {
+		return nil
+	}
+	return f.f.Close()
+}
@@ -0,0 +1,46 @@
+package httpfs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestHeadWithoutOpen(t *testing.T) {
+	c := httpfs.NewCountingFiler(newMemFS(t, map[string]string{
+		"/doc.txt": "some text",
+		"/blob":    "<html><body>sniffed</body></html>",
+	}))
+	fs := httpfs.New(c)
+
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/doc.txt", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	h := rec.Header()
+	if h.Get("Content-Length") != "9" || h.Get("Last-Modified") == "" || h.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("headers = %v", h)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q", rec.Body)
+	}
+	if n := c.Counts()["OpenFile"]; n != 0 {
+		t.Errorf("HEAD opened the file %d times", n)
+	}
+
+	// without an extension the content type is sniffed from the file
+	rec = httptest.NewRecorder()
+	fs.ServeFile(rec, httptest.NewRequest(http.MethodHead, "/", nil), "/blob")
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" || rec.Body.Len() != 0 {
+		t.Errorf("sniffed Content-Type = %q, body %q", ct, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	fs.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/missing.txt", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing file status = %d", rec.Code)
+	}
+}
@@ -1,10 +1,20 @@
 package httpfs
 
 import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,39 +25,217 @@ import (
 var ErrNotImplemented = errors.New("not implemented")
 
 type Httpfs struct {
-	fs absfs.Filer
+	fs   absfs.Filer
+	opts []Option
+
+	name          string
+	compression   *compressor
+	lstatListings bool
+	charset       string
+	contentType   func(name string, info os.FileInfo) string
+	validator     func(name string, info os.FileInfo) (etag string, lastMod time.Time)
+	etag          bool
+	strongETag    *digester
+	sidecars      bool
+	noByteServing bool
+	readRate      int64
+	digest        *digester
+
+	listTimeLayout   string
+	listTimeLocation *time.Location
+	listingTemplate  *template.Template
+
+	listingAuthorizer func(r *http.Request, dir string) bool
+	authorizeFiles    bool
+	noListing         bool
+	cleanURLs         bool
+	noSlashPaths      bool
+	hideDotfiles      bool
+	allowedPrefixes   []string
+	errorHandler      func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+	prefix        string
+	rewrite       func(name string) string
+	allowSymlinks bool
+	denyGlobs     []string
+	globBase      string
+	allowedExts   []string
+	tempDir       string
+	maxUploadSize int64
+	readOnly      bool
+	quota         *quota
+	dirSizes      bool
+	strictSync    bool
+	syncOnClose   bool
+	idempotency   *idempotencyCache
+	cache         *fileCache
+
+	tolerateReaddirErrors bool
+
+	slowThreshold time.Duration
+	slowOps       [numOps]atomic.Int64
+	observer      Observer
+
+	retries    *retryPolicy
+	sem        chan struct{}
+	semTimeout time.Duration
+
+	locks []sync.RWMutex
 }
 
-func New(fs absfs.Filer) *Httpfs {
-	return &Httpfs{fs}
+// pathError returns err, from the operation op on the file name, as a
+// *os.PathError. Errors that already are one are returned unchanged.
+func pathError(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*os.PathError); ok {
+		return err
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
 }
 
-func (filer *Httpfs) Open(name string) (http.File, error) {
-	f, err := filer.OpenFile(name, os.O_RDONLY, 0400)
+// An Option configures an Httpfs.
+type Option func(*Httpfs)
 
-	return http.File(f), err
+func New(fs absfs.Filer, opts ...Option) *Httpfs {
+	filer := &Httpfs{fs: fs, opts: opts}
+	for _, opt := range opts {
+		opt(filer)
+	}
+	return filer
+}
+
+func (filer *Httpfs) Open(name string) (http.File, error) {
+	return filer.OpenContext(context.Background(), name)
 }
 
 // OpenFile opens a file using the given flags and the given mode.
 func (filer *Httpfs) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
-	return filer.fs.OpenFile(name, flag, perm)
+	return filer.openFileContext(context.Background(), name, flag, perm)
+}
+
+// openFileContext opens a file as OpenFile does, giving up retries set with
+// WithRetry once ctx is done.
+func (filer *Httpfs) openFileContext(ctx context.Context, name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if filer.hidden(name) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := filer.open(ctx, name, flag, perm)
+	if err != nil || !filer.hideDotfiles {
+		return f, err
+	}
+	return &dotfileFilter{File: f}, nil
+}
+
+// Create creates or truncates the named file, opening it for reading and
+// writing, as os.Create does.
+func (filer *Httpfs) Create(name string) (absfs.File, error) {
+	return filer.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+}
+
+// openFile opens a file as OpenFile does, hidden or not, without hiding
+// dotfiles from Readdir.
+func (filer *Httpfs) openFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	return filer.open(context.Background(), name, flag, perm)
+}
+
+// open opens a file as openFile does, giving up retries once ctx is done.
+func (filer *Httpfs) open(ctx context.Context, name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if err := filer.acquireContext(ctx); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	defer filer.lock(p, isWrite(flag))()
+	if isWrite(flag) {
+		if err := filer.checkWrite(name); err != nil {
+			return nil, err
+		}
+		if filer.cache != nil {
+			filer.cache.forget(name)
+		}
+	}
+	var size int64
+	if filer.quota != nil && isWrite(flag) {
+		if info, err := filer.fs.Stat(p); err == nil {
+			size = info.Size()
+		}
+	}
+	var f absfs.File
+	err = filer.retry(ctx, flag&os.O_EXCL == 0, func() error {
+		start := filer.startOp()
+		f, err = filer.fs.OpenFile(p, flag, perm)
+		filer.endOp(opOpen, name, start, err)
+		return err
+	})
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	if filer.sem != nil {
+		f = &limitedFile{File: f, ctx: ctx, filer: filer}
+	}
+	if filer.quota != nil && isWrite(flag) {
+		if flag&os.O_TRUNC != 0 {
+			filer.quota.refund(p, size)
+			size = 0
+		}
+		f = newQuotaFile(f, filer.quota, name, p, flag, size)
+	}
+	if filer.syncOnClose && isWrite(flag) {
+		f = &syncingFile{File: f, name: name, strict: filer.strictSync}
+	}
+	if filer.retries != nil {
+		f = &retryFile{File: f, ctx: ctx, filer: filer}
+	}
+	return f, nil
 }
 
 // Mkdir creates a directory in the filesystem, return an error if any
-// happens.
+// happens. If name exists as a file the error wraps syscall.ENOTDIR.
 func (filer *Httpfs) Mkdir(name string, perm os.FileMode) error {
-	return filer.fs.Mkdir(name, perm)
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	defer filer.release()
+	if err := filer.checkReadOnly("mkdir", name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	if info, err := filer.fs.Stat(p); err == nil && !info.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	start := filer.startOp()
+	err = filer.fs.Mkdir(p, perm)
+	filer.endOp(opMkdir, name, start, err)
+	return pathError("mkdir", name, err)
 }
 
 // MkdirAll creates all missing directories in `name` without returning an error
 // for directories that already exist
 func (filer *Httpfs) MkdirAll(name string, perm os.FileMode) error {
-	p := string(filepath.Separator)
-	for _, name := range strings.Split(name, p) {
+	name = filer.slashPath(name)
+	clean, err := cleanPath("mkdir", name)
+	if err != nil {
+		return err
+	}
+	p := "/"
+	for _, name := range strings.Split(name, "/") {
 		if name == "" {
 			continue
 		}
-		p = filepath.Join(p, name)
+		p = path.Join(p, name)
+		if p != clean && !filer.allowed(p) {
+			// a parent outside the allowed prefixes, which must exist
+			continue
+		}
 		err := filer.Mkdir(p, perm)
 		if err != nil && !os.IsExist(err) {
 			return err
@@ -59,65 +247,414 @@ func (filer *Httpfs) MkdirAll(name string, perm os.FileMode) error {
 // Remove removes a file identified by name, returning an error, if any
 // happens.
 func (filer *Httpfs) Remove(name string) error {
-	return filer.fs.Remove(name)
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	defer filer.release()
+	if err := filer.checkReadOnly("remove", name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	start := filer.startOp()
+	err = filer.free(p, func() error { return filer.fs.Remove(p) })
+	filer.endOp(opRemove, name, start, err)
+	return pathError("remove", name, err)
 }
 
 // RemoveAll removes a directory after removing all children of that directory.
 func (filer *Httpfs) RemoveAll(path string) (err error) {
-	info, err := filer.Stat(path)
+	return filer.RemoveAllFunc(path, removeAlways)
+}
+
+// RemoveAllFunc removes path and its children as RemoveAll does, but only
+// those fn agrees to remove. fn is called for each file and directory in the
+// tree, a directory before its children. If fn returns false for a file it
+// is kept, and for a directory, the directory and all its children are kept.
+// A directory fn agrees to remove is kept anyway if any of its children are.
+// An error from fn stops the removal and is returned. A dry run is a fn that
+// records the names it is passed and returns false.
+func (filer *Httpfs) RemoveAllFunc(path string, fn func(name string, info os.FileInfo) (remove bool, err error)) error {
+	if err := filer.checkReadOnly("removeall", path); err != nil {
+		return err
+	}
+	if _, err := cleanPath("removeall", filer.slashPath(path)); err != nil {
+		return err
+	}
+	if filer.hidden(path) {
+		return nil
+	}
+	_, err := filer.removeTree(path, nil, fn)
+	return err
+}
+
+// removeAlways agrees to remove every file.
+func removeAlways(string, os.FileInfo) (bool, error) {
+	return true, nil
+}
+
+// maxRemoveDepth bounds the depth of the directories removed by RemoveAll,
+// which could otherwise loop forever on filers that follow symbolic links
+// without reporting them and whose FileInfos os.SameFile cannot compare.
+const maxRemoveDepth = 255
+
+// removeAll removes path and any children it contains, including hidden
+// dotfiles. Symbolic links are removed, not followed.
+func (filer *Httpfs) removeAll(path string) error {
+	_, err := filer.removeTree(path, nil, removeAlways)
+	return err
+}
+
+// removeTree removes path and the children fn agrees to remove, reporting
+// whether path is gone. visited holds the directories path is in, from the
+// top of the tree down. A directory that is the same file as one of them was
+// reached through a symbolic link the filer follows without reporting it,
+// and the link is removed rather than the directory.
+func (filer *Httpfs) removeTree(path string, visited []os.FileInfo, fn func(string, os.FileInfo) (bool, error)) (bool, error) {
+	info, err := filer.lstat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return true, nil
 		}
-		return err
+		return false, err
+	}
+	remove, err := fn(path, info)
+	if !remove || err != nil {
+		return false, err
 	}
 
 	// if it's not a directory remove it and return
-	if !info.IsDir() {
-		return filer.Remove(path)
+	if !info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+		return true, filer.Remove(path)
+	}
+	for _, dir := range visited {
+		if os.SameFile(dir, info) {
+			return true, filer.Remove(path)
+		}
+	}
+	if len(visited) > maxRemoveDepth {
+		return false, &os.PathError{Op: "removeall", Path: path, Err: syscall.ELOOP}
 	}
 
-	f, err := filer.OpenFile(path, os.O_RDWR, 0700)
+	f, err := filer.openFile(path, os.O_RDONLY, 0)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return true, nil
 		}
-		return err
+		return false, err
 	}
 
 	// get and loop through each directory entry calling remove all recursively
-	infos, err := f.Readdir(0)
-	if err != nil {
-		return err
-	}
+	infos, rerr := filer.readdir(f)
 	f.Close()
 
+	visited = append(visited[:len(visited):len(visited)], info)
+	empty := true
 	for _, info := range infos {
-		err = filer.RemoveAll(filepath.Join(path, info.Name()))
+		removed, err := filer.removeTree(filepath.Join(path, info.Name()), visited, fn)
 		if err != nil {
-			return err
+			return false, err
+		}
+		empty = empty && removed
+	}
+	if rerr != nil {
+		return false, rerr
+	}
+	if !empty {
+		return false, nil
+	}
+
+	return true, filer.Remove(path)
+}
+
+// ReadDir reads the named directory and returns its entries sorted by
+// filename. If name is not a directory ReadDir returns a *os.PathError
+// wrapping syscall.ENOTDIR, whatever the underlying filer would do.
+func (filer *Httpfs) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := filer.listDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+// ReadDirInfo reads the named directory as ReadDir does, but returns the full
+// FileInfo of each entry, read along with the entries in a single pass.
+func (filer *Httpfs) ReadDirInfo(name string) ([]os.FileInfo, error) {
+	return filer.listDir(name)
+}
+
+// readDirBatch is the number of entries ReadDirIter reads at a time.
+const readDirBatch = 1000
+
+// ReadDirIter calls fn for each entry of the named directory, reading the
+// entries in batches so that huge directories are listed in bounded memory.
+// Unlike ReadDir it does not sort the entries, which come in the order the
+// underlying filer returns them. If fn returns fs.SkipAll, ReadDirIter stops
+// and returns nil; any other error from fn stops it and is returned.
+func (filer *Httpfs) ReadDirIter(name string, fn func(fs.DirEntry) error) error {
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	for {
+		infos, rerr := f.Readdir(readDirBatch)
+		for _, info := range infos {
+			err := fn(fs.FileInfoToDirEntry(info))
+			if err == fs.SkipAll {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		switch {
+		case rerr == io.EOF, rerr != nil && filer.tolerateReaddirErrors:
+			return nil
+		case rerr != nil:
+			return rerr
+		case len(infos) == 0:
+			return nil
 		}
 	}
+}
+
+// ReadFile reads the named file and returns its contents.
+func (filer *Httpfs) ReadFile(name string) ([]byte, error) {
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	return filer.Remove(path)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, info.Size()+bytes.MinRead))
+	_, err = buf.ReadFrom(f)
+	return buf.Bytes(), err
+}
+
+// readDirInfos returns the FileInfo of each entry of the directory name
+// sorted by filename, including hidden dotfiles.
+func (filer *Httpfs) readDirInfos(name string) ([]os.FileInfo, error) {
+	info, err := filer.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	f, err := filer.openFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := filer.readdir(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// readdir reads all entries of the open directory f. Entries returned along
+// with an error are kept, and the error itself is dropped when Readdir errors
+// are tolerated.
+func (filer *Httpfs) readdir(f absfs.File) ([]os.FileInfo, error) {
+	infos, err := f.Readdir(0)
+	if err == io.EOF || filer.tolerateReaddirErrors {
+		err = nil
+	}
+	return infos, err
+}
+
+// WithTolerateReaddirErrors makes directory listings and RemoveAll carry on
+// with the entries a Readdir returned alongside an error, as some filers do
+// when a directory changes while it is read, instead of failing.
+func WithTolerateReaddirErrors(tolerate bool) Option {
+	return func(filer *Httpfs) {
+		filer.tolerateReaddirErrors = tolerate
+	}
 }
 
 // Stat returns the FileInfo structure describing file. If there is an error, it will be of type *PathError.
 func (filer *Httpfs) Stat(name string) (os.FileInfo, error) {
-	return filer.fs.Stat(name)
+	if filer.hidden(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return filer.stat(name)
+}
+
+// stat returns the FileInfo of the file name, hidden or not.
+func (filer *Httpfs) stat(name string) (os.FileInfo, error) {
+	if err := filer.acquire(); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	defer filer.lock(p, false)()
+	start := filer.startOp()
+	info, err := filer.fs.Stat(p)
+	filer.endOp(opStat, name, start, err)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return info, nil
 }
 
 //Chmod changes the mode of the named file to mode.
 func (filer *Httpfs) Chmod(name string, mode os.FileMode) error {
-	return filer.fs.Chmod(name, mode)
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	defer filer.release()
+	if err := filer.checkReadOnly("chmod", name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("chmod", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	start := filer.startOp()
+	err = filer.fs.Chmod(p, mode)
+	filer.endOp(opChmod, name, start, err)
+	return pathError("chmod", name, err)
 }
 
-//Chtimes changes the access and modification times of the named file
+//Chtimes changes the access and modification times of the named file. If
+// the underlying filer reports that it does not support Chtimes, the times
+// are set through the open file if it can, and otherwise Chtimes fails with
+// ErrNotSupported.
 func (filer *Httpfs) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	return filer.fs.Chtimes(name, atime, mtime)
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+	defer filer.release()
+	if err := filer.checkReadOnly("chtimes", name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("chtimes", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	start := filer.startOp()
+	err = filer.fs.Chtimes(p, atime, mtime)
+	if unsupported(err) {
+		err = filer.setTimes(p, atime, mtime)
+	}
+	filer.endOp(opChtimes, name, start, err)
+	return pathError("chtimes", name, err)
 }
 
 //Chown changes the owner and group ids of the named file
 func (filer *Httpfs) Chown(name string, uid, gid int) error {
-	return filer.fs.Chown(name, uid, gid)
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "chown", Path: name, Err: err}
+	}
+	defer filer.release()
+	if err := filer.checkReadOnly("chown", name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("chown", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	start := filer.startOp()
+	err = filer.fs.Chown(p, uid, gid)
+	filer.endOp(opChown, name, start, err)
+	return pathError("chown", name, err)
+}
+
+// truncater is implemented by filers that can truncate a file by name.
+type truncater interface {
+	Truncate(name string, size int64) error
+}
+
+// Truncate changes the size of the named file, extending it with zeros if it
+// grows. It delegates to the underlying filer if it can truncate by name, and
+// otherwise opens the file for writing and truncates it. Either way the file
+// is checked as a write to it would be.
+func (filer *Httpfs) Truncate(name string, size int64) error {
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: name, Err: os.ErrInvalid}
+	}
+	if filer.hidden(name) {
+		return &os.PathError{Op: "truncate", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := filer.checkWrite(name); err != nil {
+		return err
+	}
+	t, ok := filer.fs.(truncater)
+	if !ok {
+		f, err := filer.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		err = f.Truncate(size)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return pathError("truncate", name, err)
+	}
+
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "truncate", Path: name, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("truncate", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	var delta int64
+	if filer.quota != nil {
+		info, err := filer.fs.Stat(p)
+		if err != nil {
+			return pathError("truncate", name, err)
+		}
+		delta = size - info.Size()
+		if delta > 0 && !filer.quota.charge(p, delta) {
+			return &os.PathError{Op: "truncate", Path: name, Err: ErrQuotaExceeded}
+		}
+	}
+	start := filer.startOp()
+	err = t.Truncate(p, size)
+	filer.endOp(opTruncate, name, start, err)
+	switch {
+	case err != nil && delta > 0:
+		filer.quota.refund(p, delta)
+	case err == nil && delta < 0:
+		filer.quota.refund(p, -delta)
+	}
+	return pathError("truncate", name, err)
 }
@@ -1,12 +1,18 @@
 package httpfs_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"reflect"
+	"syscall"
+	"time"
 
+	"github.com/absfs/absfs"
 	"github.com/absfs/httpfs"
 	"github.com/absfs/memfs"
 
@@ -45,3 +51,266 @@ func TestFileServer(t *testing.T) {
 	}
 	t.Logf("received: %q", string(data))
 }
+
+func TestTruncate(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/file.txt": "hello world"}))
+
+	for _, size := range []int64{5, 20, 0} {
+		if err := fs.Truncate("/file.txt", size); err != nil {
+			t.Fatal(err)
+		}
+		info, err := fs.Stat("/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != size {
+			t.Errorf("size = %d, want %d", info.Size(), size)
+		}
+	}
+
+	err := fs.Truncate("/missing.txt", 0)
+	if _, ok := err.(*os.PathError); !ok || !os.IsNotExist(err) {
+		t.Errorf("missing file: err = %#v, want *os.PathError not exist", err)
+	}
+	if _, err := fs.Stat("/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Truncate created the missing file: %v", err)
+	}
+}
+
+// truncateFS adds Truncate by name to a filer, counting the calls.
+type truncateFS struct {
+	absfs.Filer
+	calls int
+}
+
+func (fs *truncateFS) Truncate(name string, size int64) error {
+	fs.calls++
+	f, err := fs.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(size)
+}
+
+func TestTruncateChecks(t *testing.T) {
+	files := map[string]string{"/a.txt": "a", "/a.php": "php", "/.env": "secret"}
+	for _, delegated := range []bool{true, false} {
+		var o countingObserver
+		var tfs *truncateFS
+		var backend absfs.Filer = newMemFS(t, files)
+		if delegated {
+			tfs = &truncateFS{Filer: backend}
+			backend = tfs
+		}
+		fs := httpfs.New(backend,
+			httpfs.WithDenyGlobs("*.php"),
+			httpfs.WithHideDotfiles(true),
+			httpfs.WithObserver(&o))
+
+		if err := fs.Truncate("/a.php", 0); !os.IsPermission(err) {
+			t.Errorf("delegated %v: truncating a denied file: err = %v", delegated, err)
+		}
+		if err := fs.Truncate("/.env", 0); !os.IsNotExist(err) {
+			t.Errorf("delegated %v: truncating a hidden file: err = %v", delegated, err)
+		}
+		if err := fs.Truncate("/a.txt", 0); err != nil {
+			t.Errorf("delegated %v: %v", delegated, err)
+		}
+		if delegated && (tfs.calls != 1 || o.calls["truncate"] != 1) {
+			t.Errorf("delegated truncations = %d, observed %d; want 1", tfs.calls, o.calls["truncate"])
+		}
+	}
+}
+
+var errBare = errors.New("backend failure")
+
+// bareErrFS fails every operation with errBare instead of a *os.PathError.
+type bareErrFS struct{}
+
+func (bareErrFS) OpenFile(string, int, os.FileMode) (absfs.File, error) { return nil, errBare }
+func (bareErrFS) Mkdir(string, os.FileMode) error                       { return errBare }
+func (bareErrFS) Remove(string) error                                   { return errBare }
+func (bareErrFS) Stat(string) (os.FileInfo, error)                      { return nil, errBare }
+func (bareErrFS) Chmod(string, os.FileMode) error                       { return errBare }
+func (bareErrFS) Chtimes(string, time.Time, time.Time) error            { return errBare }
+func (bareErrFS) Chown(string, int, int) error                          { return errBare }
+func (bareErrFS) Truncate(string, int64) error                          { return errBare }
+func (bareErrFS) Rename(string, string) error                           { return errBare }
+
+func TestPathErrors(t *testing.T) {
+	fs := httpfs.New(bareErrFS{})
+	now := time.Now()
+
+	for op, call := range map[string]func() error{
+		"open":     func() error { _, err := fs.OpenFile("/f", os.O_RDONLY, 0); return err },
+		"mkdir":    func() error { return fs.Mkdir("/f", 0755) },
+		"remove":   func() error { return fs.Remove("/f") },
+		"stat":     func() error { _, err := fs.Stat("/f"); return err },
+		"chmod":    func() error { return fs.Chmod("/f", 0644) },
+		"chtimes":  func() error { return fs.Chtimes("/f", now, now) },
+		"chown":    func() error { return fs.Chown("/f", 0, 0) },
+		"truncate": func() error { return fs.Truncate("/f", 0) },
+	} {
+		var perr *os.PathError
+		if err := call(); !errors.As(err, &perr) {
+			t.Errorf("%s: err = %#v, want *os.PathError", op, err)
+			continue
+		}
+		if perr.Op != op || perr.Path != "/f" || perr.Err != errBare {
+			t.Errorf("%s: got %#v", op, perr)
+		}
+	}
+
+	var lerr *os.LinkError
+	if err := fs.Rename("/f", "/g"); !errors.As(err, &lerr) {
+		t.Errorf("rename: err = %#v, want *os.LinkError", err)
+	} else if lerr.Op != "rename" || lerr.Old != "/f" || lerr.New != "/g" || lerr.Err != errBare {
+		t.Errorf("rename: got %#v", lerr)
+	}
+
+	mfs := newMemFS(t, nil)
+	_, err := httpfs.New(mfs).Stat("/missing")
+	if perr, ok := err.(*os.PathError); !ok || !os.IsNotExist(perr) {
+		t.Errorf("backend *os.PathError not passed through: %#v", err)
+	}
+}
+
+// newMemFS returns a memfs populated with files, creating parent directories
+// as needed.
+func newMemFS(t *testing.T, files map[string]string) absfs.Filer {
+	t.Helper()
+	mfs, err := memfs.NewFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := httpfs.New(mfs)
+	for name, data := range files {
+		err = fs.MkdirAll(path.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.Write([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	return mfs
+}
+
+func TestReadDirInfo(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/dir/b.txt":     "bb",
+		"/dir/a.txt":     "a",
+		"/dir/sub/c.txt": "ccc",
+	}))
+
+	infos, err := fs.ReadDirInfo("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+		want, err := fs.Stat("/dir/" + info.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != want.Size() || info.Mode() != want.Mode() || info.IsDir() != want.IsDir() || !info.ModTime().Equal(want.ModTime()) {
+			t.Errorf("%s: ReadDirInfo = %v %v %v, Stat = %v %v %v", info.Name(),
+				info.Size(), info.Mode(), info.ModTime(), want.Size(), want.Mode(), want.ModTime())
+		}
+	}
+	if want := []string{"a.txt", "b.txt", "sub"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+
+	if _, err := fs.ReadDirInfo("/dir/a.txt"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("ReadDirInfo of a file: %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a.txt": "old contents"}))
+
+	f, err := fs.Create("/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if got := readFile(t, fs, "/a.txt"); got != "new" {
+		t.Errorf("a.txt = %q, want %q", got, "new")
+	}
+
+	f, err = fs.Create("/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if info, err := fs.Stat("/b.txt"); err != nil || info.Size() != 0 {
+		t.Errorf("Stat of created file = %v, %v", info, err)
+	}
+}
+
+func TestRemoveAllFunc(t *testing.T) {
+	files := map[string]string{
+		"/dir/keep.txt":     "",
+		"/dir/drop.log":     "",
+		"/dir/sub/drop.log": "",
+		"/dir/kept/a.log":   "",
+	}
+	fs := httpfs.New(newMemFS(t, files))
+
+	// a dry run visits everything and removes nothing
+	var visited []string
+	err := fs.RemoveAllFunc("/dir", func(name string, info os.FileInfo) (bool, error) {
+		visited = append(visited, name)
+		return false, nil
+	})
+	if err != nil || !reflect.DeepEqual(visited, []string{"/dir"}) {
+		t.Errorf("dry run visited %q, err %v", visited, err)
+	}
+
+	err = fs.RemoveAllFunc("/dir", func(name string, info os.FileInfo) (bool, error) {
+		if info.IsDir() {
+			return name != "/dir/kept", nil
+		}
+		return path.Ext(name) == ".log", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"/dir/keep.txt":   true,
+		"/dir/drop.log":   false,
+		"/dir/sub":        false,
+		"/dir/kept/a.log": true,
+	} {
+		if _, err := fs.Stat(name); (err == nil) != want {
+			t.Errorf("%s: exists = %v, want %v", name, err == nil, want)
+		}
+	}
+
+	errStop := errors.New("stop")
+	err = fs.RemoveAllFunc("/dir", func(name string, info os.FileInfo) (bool, error) {
+		if name == "/dir/keep.txt" {
+			return true, errStop
+		}
+		return true, nil
+	})
+	if err != errStop {
+		t.Errorf("err = %v, want %v", err, errStop)
+	}
+	if _, err := fs.Stat("/dir/keep.txt"); err != nil {
+		t.Errorf("file removed despite the error: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package httpfs
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithIdempotency makes the handler honor the Idempotency-Key header on
+// uploads. Completed uploads are remembered for ttl, and a retry with the same
+// key and body returns the earlier result without writing again, while a
+// retry with the same key and a different body or path fails with 409
+// Conflict, as does one made while the upload using the key is in progress.
+func WithIdempotency(ttl time.Duration) Option {
+	return func(filer *Httpfs) {
+		filer.idempotency = &idempotencyCache{ttl: ttl, results: make(map[string]idempotentResult)}
+	}
+}
+
+type idempotentResult struct {
+	sum     [sha256.Size]byte
+	status  int
+	expires time.Time
+}
+
+type idempotencyCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	results map[string]idempotentResult
+}
+
+// reserve returns the unexpired result stored for key, if there is one.
+// Otherwise it reserves key for an upload in progress, which must be ended
+// with store or release, and returns false. The result for a reserved key
+// has a zero status.
+func (c *idempotencyCache) reserve(key string) (idempotentResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, res := range c.results {
+		if res.status != 0 && now.After(res.expires) {
+			delete(c.results, k)
+		}
+	}
+	res, ok := c.results[key]
+	if !ok {
+		c.results[key] = idempotentResult{}
+	}
+	return res, ok
+}
+
+func (c *idempotencyCache) store(key string, res idempotentResult) {
+	c.mu.Lock()
+	c.results[key] = res
+	c.mu.Unlock()
+}
+
+// release drops the reservation of key after a failed upload, so that it may
+// be retried.
+func (c *idempotencyCache) release(key string) {
+	c.mu.Lock()
+	delete(c.results, key)
+	c.mu.Unlock()
+}
+
+// serve answers the upload r of the file name made with the idempotency key,
+// calling write to perform it unless it was already completed. An upload
+// made with a key while another using it is in progress fails with 409
+// Conflict. If write fails serve returns its error, leaving the caller to
+// answer the request.
+func (c *idempotencyCache) serve(w http.ResponseWriter, r *http.Request, key, name string, write func(body io.Reader) (int, error)) error {
+	h := sha256.New()
+	io.WriteString(h, name)
+	h.Write([]byte{0})
+
+	var res idempotentResult
+	if prev, ok := c.reserve(key); ok {
+		if prev.status == 0 {
+			http.Error(w, "409 Conflict", http.StatusConflict)
+			return nil
+		}
+		_, err := io.Copy(h, r.Body)
+		if err != nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return nil
+		}
+		h.Sum(res.sum[:0])
+		if res.sum != prev.sum {
+			http.Error(w, "409 Conflict", http.StatusConflict)
+			return nil
+		}
+		w.WriteHeader(prev.status)
+		return nil
+	}
+
+	status, err := write(io.TeeReader(r.Body, h))
+	if err != nil {
+		c.release(key)
+		return err
+	}
+	h.Sum(res.sum[:0])
+	res.status = status
+	res.expires = time.Now().Add(c.ttl)
+	c.store(key, res)
+	w.WriteHeader(status)
+	return nil
+}
@@ -0,0 +1,108 @@
+package httpfs_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestIdempotentUpload(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, nil), httpfs.WithIdempotency(time.Minute))
+
+	put := func(name, key, body string) int {
+		req := httptest.NewRequest("PUT", name, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := put("/doc.txt", "k1", "one"); code != http.StatusCreated {
+		t.Fatalf("first PUT: status = %d, want %d", code, http.StatusCreated)
+	}
+	if got := readFile(t, fs, "/doc.txt"); got != "one" {
+		t.Fatalf("content = %q", got)
+	}
+
+	// change the file behind the handler's back to detect a rewrite
+	f, err := fs.OpenFile("/doc.txt", os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("edited"))
+	f.Close()
+
+	if code := put("/doc.txt", "k1", "one"); code != http.StatusCreated {
+		t.Errorf("retried PUT: status = %d, want cached %d", code, http.StatusCreated)
+	}
+	if got := readFile(t, fs, "/doc.txt"); got != "edited" {
+		t.Errorf("retried PUT rewrote the file: %q", got)
+	}
+
+	if code := put("/doc.txt", "k1", "two"); code != http.StatusConflict {
+		t.Errorf("conflicting PUT: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := put("/other.txt", "k1", "one"); code != http.StatusConflict {
+		t.Errorf("PUT to another path: status = %d, want %d", code, http.StatusConflict)
+	}
+
+	if code := put("/doc.txt", "", "three"); code != http.StatusNoContent {
+		t.Errorf("PUT without key: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if got := readFile(t, fs, "/doc.txt"); got != "three" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+// blockingReader returns its data once released, signalling started when
+// first read.
+type blockingReader struct {
+	started, release chan struct{}
+	data             io.Reader
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if r.started != nil {
+		close(r.started)
+		r.started = nil
+		<-r.release
+	}
+	return r.data.Read(p)
+}
+
+func TestIdempotentUploadInProgress(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, nil), httpfs.WithIdempotency(time.Minute))
+
+	put := func(body io.Reader) int {
+		req := httptest.NewRequest("PUT", "/doc.txt", body)
+		req.Header.Set("Idempotency-Key", "k1")
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	body := &blockingReader{started: make(chan struct{}), release: make(chan struct{}), data: strings.NewReader("one")}
+	started := body.started
+	first := make(chan int)
+	go func() { first <- put(body) }()
+	<-started
+
+	if code := put(strings.NewReader("one")); code != http.StatusConflict {
+		t.Errorf("PUT during upload: status = %d, want %d", code, http.StatusConflict)
+	}
+	close(body.release)
+	if code := <-first; code != http.StatusCreated {
+		t.Errorf("first PUT: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := put(strings.NewReader("one")); code != http.StatusCreated {
+		t.Errorf("retried PUT: status = %d, want cached %d", code, http.StatusCreated)
+	}
+}
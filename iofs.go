@@ -0,0 +1,145 @@
+package httpfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/pkg/errors"
+)
+
+// ErrReadOnly is returned by operations that would modify a filesystem
+// created with NewFromFS. The handler answers it with 403 Forbidden.
+var ErrReadOnly = errors.New("read-only filesystem")
+
+// NewFromFS returns an Httpfs serving the files of fsys, such as an embed.FS,
+// configured with opts. Opening and statting files and reading directories
+// use fsys, and the fs.StatFS and fs.ReadDirFile interfaces where it
+// implements them. Operations that would modify fsys fail with ErrReadOnly.
+func NewFromFS(fsys fs.FS, opts ...Option) *Httpfs {
+	return New(&iofsFiler{fsys: fsys}, opts...)
+}
+
+// iofsFiler adapts an fs.FS to absfs.Filer.
+type iofsFiler struct {
+	fsys fs.FS
+}
+
+// fsName returns the fs.FS name of the filer path name.
+func fsName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (f *iofsFiler) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if isWrite(flag) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	file, err := f.fsys.Open(fsName(name))
+	if err != nil {
+		return nil, err
+	}
+	return &iofsFile{File: file, name: name}, nil
+}
+
+func (f *iofsFiler) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, fsName(name))
+}
+
+func (f *iofsFiler) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+func (f *iofsFiler) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (f *iofsFiler) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrReadOnly}
+}
+
+func (f *iofsFiler) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: ErrReadOnly}
+}
+
+func (f *iofsFiler) Chown(name string, uid, gid int) error {
+	return &os.PathError{Op: "chown", Path: name, Err: ErrReadOnly}
+}
+
+// iofsFile adapts an fs.File to absfs.File. Seeking and ReadAt need the file
+// to implement io.Seeker and io.ReaderAt, as the files of embed.FS and
+// fstest.MapFS do, and fail with ErrNotSupported otherwise.
+type iofsFile struct {
+	fs.File
+	name string
+}
+
+func (f *iofsFile) Name() string { return f.name }
+
+func (f *iofsFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: ErrNotSupported}
+	}
+	return s.Seek(offset, whence)
+}
+
+func (f *iofsFile) ReadAt(b []byte, off int64) (int, error) {
+	r, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: ErrNotSupported}
+	}
+	return r.ReadAt(b, off)
+}
+
+func (f *iofsFile) Readdir(n int) ([]os.FileInfo, error) {
+	d, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+	entries, err := d.ReadDir(n)
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, ierr := e.Info()
+		if ierr != nil {
+			return infos, ierr
+		}
+		infos = append(infos, info)
+	}
+	return infos, err
+}
+
+func (f *iofsFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+func (f *iofsFile) Sync() error { return nil }
+
+func (f *iofsFile) Write(b []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *iofsFile) WriteAt(b []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *iofsFile) WriteString(s string) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *iofsFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: ErrReadOnly}
+}
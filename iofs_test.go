@@ -0,0 +1,70 @@
+package httpfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestNewFromFS(t *testing.T) {
+	fs := httpfs.NewFromFS(fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>")},
+		"css/site.css":    {Data: []byte("body{}")},
+		"css/print.css":   {Data: []byte("")},
+		"docs/readme.txt": {Data: []byte("read me")},
+	})
+
+	info, err := fs.Stat("/css/site.css")
+	if err != nil || info.Size() != 6 {
+		t.Fatalf("Stat = %v, %v", info, err)
+	}
+	entries, err := fs.ReadDir("/css")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "print.css" {
+		t.Errorf("ReadDir = %v, %v", entries, err)
+	}
+
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+	for name, want := range map[string]string{
+		"/":                "<h1>home</h1>",
+		"/docs/readme.txt": "read me",
+		"/docs/":           "readme.txt",
+	} {
+		res, err := http.Get(srv.URL + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s = %d %q, want %q", name, res.StatusCode, body, want)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/new.txt", strings.NewReader("x"))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("PUT status = %d, want 403", res.StatusCode)
+	}
+
+	if err := fs.Mkdir("/dir", 0755); !errors.Is(err, httpfs.ErrReadOnly) {
+		t.Errorf("Mkdir err = %v", err)
+	}
+	if err := fs.Remove("/index.html"); !errors.Is(err, httpfs.ErrReadOnly) {
+		t.Errorf("Remove err = %v", err)
+	}
+	if _, err := fs.OpenFile("/index.html", os.O_RDWR, 0); !errors.Is(err, httpfs.ErrReadOnly) {
+		t.Errorf("OpenFile for writing err = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package httpfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"time"
+)
+
+// jsonEntry is a directory entry as listed by JSONListingHandler.
+type jsonEntry struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	ModTime string `json:"modTime"`
+	IsDir   bool   `json:"isDir"`
+}
+
+// JSONListingHandler returns a handler that answers GET and HEAD requests for
+// directories with a JSON array of their entries sorted by name, each with
+// its name, size, mode string, RFC 3339 modtime and whether it is a
+// directory. Files are served as ServeHTTP serves them, as are requests
+// using other methods. Listings honor WithHideDotfiles and the listing
+// authorizer. Modtimes are given in the location set with
+// WithListTimeLocation, if any, but always in RFC 3339 so that clients can
+// parse them: WithListTimeFormat only applies to HTML listings, as does the
+// title set with WithFileSystemName, which the array has no place for.
+func (filer *Httpfs) JSONListingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			filer.ServeHTTP(w, r)
+			return
+		}
+		name := path.Clean("/" + r.URL.Path)
+		info, err := filer.Stat(name)
+		if err != nil {
+			filer.serveError(w, r, err)
+			return
+		}
+		if !info.IsDir() {
+			filer.serve(w, r)
+			return
+		}
+		if !filer.mayList(r, name) {
+			filer.serveStatus(w, r, http.StatusForbidden, nil)
+			return
+		}
+		filer.serveJSONListing(w, r, name)
+	})
+}
+
+// serveJSONListing writes a JSON listing of the directory name.
+func (filer *Httpfs) serveJSONListing(w http.ResponseWriter, r *http.Request, name string) {
+	infos, err := filer.listDir(name)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+
+	entries := make([]jsonEntry, len(infos))
+	for i, info := range infos {
+		modTime := info.ModTime()
+		if filer.listTimeLocation != nil {
+			modTime = modTime.In(filer.listTimeLocation)
+		}
+		entries[i] = jsonEntry{
+			Name:    info.Name(),
+			Size:    info.Size(),
+			Mode:    info.Mode().String(),
+			ModTime: modTime.Format(time.RFC3339),
+			IsDir:   info.IsDir(),
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
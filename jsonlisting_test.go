@@ -0,0 +1,100 @@
+package httpfs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestJSONListingHandler(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{
+		"/dir/b.txt":       "bbb",
+		"/dir/a.txt":       "a",
+		"/dir/.hidden":     "secret",
+		"/dir/sub/c.txt":   "c",
+		"/dir/sub/.secret": "s",
+	})
+
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	w := get(httpfs.New(mfs).JSONListingHandler(), "/dir/")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e["name"].(string))
+		for _, key := range []string{"size", "mode", "modTime", "isDir"} {
+			if _, ok := e[key]; !ok {
+				t.Errorf("%s: missing %q", e["name"], key)
+			}
+		}
+		if _, err := time.Parse(time.RFC3339, e["modTime"].(string)); err != nil {
+			t.Errorf("%s: modTime: %v", e["name"], err)
+		}
+	}
+	if want := []string{".hidden", "a.txt", "b.txt", "sub"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+	if b := entries[2]; b["size"] != 3.0 || b["isDir"] != false || b["mode"] != "-rw-r--r--" {
+		t.Errorf("b.txt = %v", b)
+	}
+	if sub := entries[3]; sub["isDir"] != true {
+		t.Errorf("sub = %v", sub)
+	}
+
+	w = get(httpfs.New(mfs, httpfs.WithHideDotfiles(true)).JSONListingHandler(), "/dir")
+	entries = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0]["name"] != "a.txt" {
+		t.Errorf("hidden dotfiles: entries = %v", entries)
+	}
+
+	// The HTML listing's title and time format do not apply, but the
+	// location does.
+	loc := time.FixedZone("UTC+5", 5*60*60)
+	w = get(httpfs.New(mfs,
+		httpfs.WithFileSystemName("files"),
+		httpfs.WithListTimeFormat("Jan 2 15:04"),
+		httpfs.WithListTimeLocation(loc)).JSONListingHandler(), "/dir")
+	entries = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("listing with HTML options: %v", err)
+	}
+	for _, e := range entries {
+		modTime, err := time.Parse(time.RFC3339, e["modTime"].(string))
+		if err != nil {
+			t.Errorf("%s: modTime with a time format: %v", e["name"], err)
+			continue
+		}
+		if _, offset := modTime.Zone(); offset != 5*60*60 {
+			t.Errorf("%s: modTime %s not in the listing location", e["name"], e["modTime"])
+		}
+	}
+
+	w = get(httpfs.New(mfs).JSONListingHandler(), "/dir/b.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "bbb" {
+		t.Errorf("file: %d %q", w.Code, w.Body.String())
+	}
+	if w := get(httpfs.New(mfs).JSONListingHandler(), "/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("missing: status = %d", w.Code)
+	}
+}
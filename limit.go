@@ -0,0 +1,149 @@
+package httpfs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/pkg/errors"
+)
+
+// ErrBusy is returned when an operation could not get a concurrency slot in
+// time. The handler answers it with 503 Service Unavailable.
+var ErrBusy = errors.New("filesystem busy")
+
+// WithConcurrencyLimit allows at most n calls to run against the underlying
+// filer at once: opening, stating and modifying files, and reading, writing,
+// listing, truncating, syncing and stating files once opened. Closing and
+// seeking files are not limited. A call waits up to timeout for a slot and
+// then fails with ErrBusy; a zero timeout fails fast. Calls made for a
+// request served over HTTP, including reads from the files it opens, stop
+// waiting and fail with the context's error once the request is done.
+func WithConcurrencyLimit(n int, timeout time.Duration) Option {
+	return func(filer *Httpfs) {
+		if n <= 0 {
+			filer.sem = nil
+			return
+		}
+		filer.sem = make(chan struct{}, n)
+		filer.semTimeout = timeout
+	}
+}
+
+// acquire takes a concurrency slot, if operations are limited. Each
+// successful acquire must be paired with a release.
+func (filer *Httpfs) acquire() error {
+	return filer.acquireContext(context.Background())
+}
+
+// acquireContext takes a slot as acquire does, giving up once ctx is done.
+func (filer *Httpfs) acquireContext(ctx context.Context) error {
+	if filer.sem == nil {
+		return nil
+	}
+	select {
+	case filer.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if filer.semTimeout <= 0 {
+		return ErrBusy
+	}
+
+	t := time.NewTimer(filer.semTimeout)
+	defer t.Stop()
+	select {
+	case filer.sem <- struct{}{}:
+		return nil
+	case <-t.C:
+		return ErrBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release gives back a slot taken by acquire.
+func (filer *Httpfs) release() {
+	if filer.sem != nil {
+		<-filer.sem
+	}
+}
+
+// limitedFile is a file opened from the underlying filer whose calls each
+// take a concurrency slot, waiting for one until ctx is done.
+type limitedFile struct {
+	absfs.File
+	ctx   context.Context
+	filer *Httpfs
+}
+
+// do runs fn, the call op on the file, holding a slot.
+func (f *limitedFile) do(op string, fn func() error) error {
+	if err := f.filer.acquireContext(f.ctx); err != nil {
+		return &os.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+	defer f.filer.release()
+	return fn()
+}
+
+func (f *limitedFile) Read(p []byte) (n int, err error) {
+	if lerr := f.do("read", func() error { n, err = f.File.Read(p); return nil }); lerr != nil {
+		return 0, lerr
+	}
+	return n, err
+}
+
+func (f *limitedFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if lerr := f.do("read", func() error { n, err = f.File.ReadAt(p, off); return nil }); lerr != nil {
+		return 0, lerr
+	}
+	return n, err
+}
+
+func (f *limitedFile) Write(p []byte) (n int, err error) {
+	if lerr := f.do("write", func() error { n, err = f.File.Write(p); return nil }); lerr != nil {
+		return 0, lerr
+	}
+	return n, err
+}
+
+func (f *limitedFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if lerr := f.do("write", func() error { n, err = f.File.WriteAt(p, off); return nil }); lerr != nil {
+		return 0, lerr
+	}
+	return n, err
+}
+
+func (f *limitedFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *limitedFile) Readdir(n int) (infos []os.FileInfo, err error) {
+	if lerr := f.do("readdir", func() error { infos, err = f.File.Readdir(n); return nil }); lerr != nil {
+		return nil, lerr
+	}
+	return infos, err
+}
+
+func (f *limitedFile) Readdirnames(n int) (names []string, err error) {
+	if lerr := f.do("readdir", func() error { names, err = f.File.Readdirnames(n); return nil }); lerr != nil {
+		return nil, lerr
+	}
+	return names, err
+}
+
+func (f *limitedFile) Stat() (info os.FileInfo, err error) {
+	if lerr := f.do("stat", func() error { info, err = f.File.Stat(); return nil }); lerr != nil {
+		return nil, lerr
+	}
+	return info, err
+}
+
+func (f *limitedFile) Truncate(size int64) error {
+	return f.do("truncate", func() error { return f.File.Truncate(size) })
+}
+
+func (f *limitedFile) Sync() error {
+	return f.do("sync", f.File.Sync)
+}
@@ -0,0 +1,109 @@
+package httpfs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	sfs := &slowStatFS{
+		Filer: newMemFS(t, map[string]string{"/a.txt": "a"}),
+		delay: 20 * time.Millisecond,
+	}
+	fs := httpfs.New(sfs, httpfs.WithConcurrencyLimit(1, time.Second))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fs.Stat("/a.txt"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sfs.peak != 1 {
+		t.Errorf("peak concurrency = %d, want 1", sfs.peak)
+	}
+
+	sfs.delay = 200 * time.Millisecond
+	fs = httpfs.New(sfs, httpfs.WithConcurrencyLimit(1, 10*time.Millisecond))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fs.Stat("/a.txt")
+	}()
+	for atomic.LoadInt32(&sfs.inflight) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	_, err := fs.Stat("/a.txt")
+	if !errors.Is(err, httpfs.ErrBusy) {
+		t.Errorf("waiter err = %v, want %v", err, httpfs.ErrBusy)
+	}
+	if waited := time.Since(start); waited > 150*time.Millisecond {
+		t.Errorf("waiter waited %v past its timeout", waited)
+	}
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/a.txt", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	<-done
+}
+
+func TestConcurrencyLimitFilesAndContext(t *testing.T) {
+	sfs := &slowStatFS{
+		Filer: newMemFS(t, map[string]string{"/a.txt": "a"}),
+		delay: 200 * time.Millisecond,
+	}
+	// hold takes the only slot of fs for the delay of a Stat.
+	hold := func(fs *httpfs.Httpfs) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fs.Stat("/a.txt")
+		}()
+		for atomic.LoadInt32(&sfs.inflight) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		return done
+	}
+
+	fs := httpfs.New(sfs, httpfs.WithConcurrencyLimit(1, 10*time.Millisecond))
+	f, err := fs.OpenFile("/a.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	done := hold(fs)
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, httpfs.ErrBusy) {
+		t.Errorf("read from an open file: err = %v, want %v", err, httpfs.ErrBusy)
+	}
+	<-done
+
+	fs = httpfs.New(sfs, httpfs.WithConcurrencyLimit(1, time.Minute))
+	done = hold(fs)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := fs.OpenContext(ctx, "/a.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if waited := time.Since(start); waited > 150*time.Millisecond {
+		t.Errorf("waiter waited %v after its context was done", waited)
+	}
+	<-done
+}
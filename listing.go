@@ -0,0 +1,320 @@
+package httpfs
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLstatListings makes directory listings Lstat each entry on filers that
+// support symbolic links, so that links are listed as links along with their
+// targets instead of as the files they point to.
+func WithLstatListings(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.lstatListings = enabled
+	}
+}
+
+// WithFileSystemName sets a name shown in the title and heading of directory
+// listings, as in "name — /path/".
+func WithFileSystemName(name string) Option {
+	return func(filer *Httpfs) {
+		filer.name = name
+	}
+}
+
+// WithListingAuthorizer shows directory listings only to requests approved by
+// authorize, answering others with 403 Forbidden. Files stay servable unless
+// WithAuthorizeFiles is also set.
+func WithListingAuthorizer(authorize func(r *http.Request, dir string) bool) Option {
+	return func(filer *Httpfs) {
+		filer.listingAuthorizer = authorize
+	}
+}
+
+// WithAuthorizeFiles makes the listing authorizer gate file requests too,
+// called with the directory containing the requested file.
+func WithAuthorizeFiles(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.authorizeFiles = enabled
+	}
+}
+
+// WithDirectoryListing enables or disables directory listings, which are
+// enabled by default. With listings disabled, requests for directories
+// without an index.html page are answered with 403 Forbidden, and Open fails
+// on them with os.ErrPermission so that http.FileServer does the same.
+func WithDirectoryListing(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.noListing = !enabled
+	}
+}
+
+// authorizeListing reports whether r may list the directory dir.
+func (filer *Httpfs) authorizeListing(r *http.Request, dir string) bool {
+	return filer.listingAuthorizer == nil || filer.listingAuthorizer(r, dir)
+}
+
+// mayList reports whether r may list the directory dir: listings must be
+// enabled and authorized.
+func (filer *Httpfs) mayList(r *http.Request, dir string) bool {
+	return !filer.noListing && filer.authorizeListing(r, dir)
+}
+
+// checkListing returns an error if the directory name, open as f, may not be
+// opened because listings are disabled and it has no index page.
+func (filer *Httpfs) checkListing(name string, f http.File) error {
+	if !filer.noListing {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+	if info, err := filer.Stat(path.Join(name, indexPage)); err == nil && !info.IsDir() {
+		return nil
+	}
+	return &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+}
+
+// defaultListTimeLayout is used for listing modtimes when only a location
+// is configured.
+const defaultListTimeLayout = "2006-01-02 15:04 MST"
+
+// WithListTimeFormat shows each entry's modtime in directory listings,
+// formatted with layout as for time.Time.Format.
+func WithListTimeFormat(layout string) Option {
+	return func(filer *Httpfs) {
+		filer.listTimeLayout = layout
+	}
+}
+
+// WithListTimeLocation shows each entry's modtime in directory listings,
+// converted to loc.
+func WithListTimeLocation(loc *time.Location) Option {
+	return func(filer *Httpfs) {
+		filer.listTimeLocation = loc
+	}
+}
+
+// listTime formats t for a directory listing. It returns "" when listings
+// do not show modtimes.
+func (filer *Httpfs) listTime(t time.Time) string {
+	if filer.listTimeLayout == "" && filer.listTimeLocation == nil {
+		return ""
+	}
+	layout := filer.listTimeLayout
+	if layout == "" {
+		layout = defaultListTimeLayout
+	}
+	if filer.listTimeLocation != nil {
+		t = t.In(filer.listTimeLocation)
+	}
+	return t.Format(layout)
+}
+
+// dirPath returns the directory name with a trailing slash.
+func dirPath(name string) string {
+	if strings.HasSuffix(name, "/") {
+		return name
+	}
+	return name + "/"
+}
+
+// dirEntry is a single entry of a directory listing.
+type dirEntry struct {
+	info os.FileInfo
+
+	// target is the target of a symbolic link, if the entry is one.
+	target string
+}
+
+var htmlReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+)
+
+// listEntry returns the listing entry for the file name whose Readdir info
+// is info.
+func (filer *Httpfs) listEntry(name string, info os.FileInfo) dirEntry {
+	entry := dirEntry{info: info}
+	if !filer.lstatListings {
+		return entry
+	}
+	l, ok := filer.fs.(lstater)
+	if !ok {
+		return entry
+	}
+	p, err := filer.resolve("lstat", name)
+	if err != nil {
+		return entry
+	}
+	linfo, err := l.Lstat(p)
+	if err != nil || linfo.Mode()&os.ModeSymlink == 0 {
+		return entry
+	}
+	entry.info = linfo
+	if r, ok := filer.fs.(readlinker); ok {
+		entry.target, _ = r.Readlink(p)
+	}
+	return entry
+}
+
+// listingBuffers pools the buffers directory listings are rendered into.
+var listingBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// maxPooledListingBuffer is the capacity beyond which a listing buffer is
+// dropped rather than pooled, so that one huge directory does not pin large
+// buffers for good.
+const maxPooledListingBuffer = 64 << 10
+
+// putListingBuffer returns buf to listingBuffers unless it grew too large.
+func putListingBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledListingBuffer {
+		return
+	}
+	listingBuffers.Put(buf)
+}
+
+// serveListing writes an HTML listing of the directory name.
+func (filer *Httpfs) serveListing(w http.ResponseWriter, r *http.Request, name string) {
+	infos, err := filer.listDir(name)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+
+	buf := listingBuffers.Get().(*bytes.Buffer)
+	defer putListingBuffer(buf)
+	buf.Reset()
+	if filer.listingTemplate != nil {
+		err = filer.listingTemplate.Execute(buf, filer.listingData(name, infos))
+		if err != nil {
+			filer.serveStatus(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	} else {
+		filer.renderListing(buf, name, infos)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// WithListingTemplate renders directory listings with tmpl in place of the
+// default listing. tmpl is executed with a *ListingData.
+func WithListingTemplate(tmpl *template.Template) Option {
+	return func(filer *Httpfs) {
+		filer.listingTemplate = tmpl
+	}
+}
+
+// ListingData is the data a listing template set with WithListingTemplate is
+// executed with.
+type ListingData struct {
+	// Path is the path of the directory, ending in a slash.
+	Path    string
+	Entries []ListingEntry
+}
+
+// ListingEntry is an entry of a directory listing.
+type ListingEntry struct {
+	Name string
+	// URL is the escaped path of the entry relative to the directory,
+	// ending in a slash for directories.
+	URL     string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	// Target is the target of a symbolic link, as listed with
+	// WithLstatListings.
+	Target string
+}
+
+// listingData returns the data a listing template is executed with for the
+// directory name holding the files infos.
+func (filer *Httpfs) listingData(name string, infos []os.FileInfo) *ListingData {
+	data := &ListingData{Path: dirPath(name), Entries: make([]ListingEntry, len(infos))}
+	for i, info := range infos {
+		entry := filer.listEntry(path.Join(name, info.Name()), info)
+		url := entry.info.Name()
+		if entry.info.IsDir() {
+			url += "/"
+		}
+		data.Entries[i] = ListingEntry{
+			Name:    entry.info.Name(),
+			URL:     escapePath(url),
+			Size:    entry.info.Size(),
+			ModTime: entry.info.ModTime(),
+			IsDir:   entry.info.IsDir(),
+			Target:  entry.target,
+		}
+	}
+	return data
+}
+
+// renderListing renders the HTML listing of the directory name holding the
+// files infos into buf.
+func (filer *Httpfs) renderListing(buf *bytes.Buffer, name string, infos []os.FileInfo) {
+	if filer.name != "" {
+		title := filer.name + " — " + dirPath(name)
+		buf.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<title>")
+		htmlReplacer.WriteString(buf, title)
+		buf.WriteString("</title>\n<h1>")
+		htmlReplacer.WriteString(buf, title)
+		buf.WriteString("</h1>\n")
+	}
+	buf.WriteString("<pre>\n")
+	for _, info := range infos {
+		entry := filer.listEntry(path.Join(name, info.Name()), info)
+		name := entry.info.Name()
+		if entry.info.IsDir() {
+			name += "/"
+		}
+		buf.WriteString("<a href=\"")
+		htmlReplacer.WriteString(buf, escapePath(name))
+		buf.WriteString("\">")
+		htmlReplacer.WriteString(buf, name)
+		buf.WriteString("</a>")
+		if entry.info.Mode()&os.ModeSymlink != 0 {
+			buf.WriteString(" -&gt; ")
+			htmlReplacer.WriteString(buf, entry.target)
+		}
+		if modTime := filer.listTime(entry.info.ModTime()); modTime != "" {
+			buf.WriteString("  ")
+			htmlReplacer.WriteString(buf, modTime)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("</pre>\n")
+}
+
+// escapePath escapes name for use as a relative URL path. name may contain
+// '?' or '#', which must be escaped to remain part of the URL path, and not
+// indicate the start of a query string or fragment.
+func escapePath(name string) string {
+	for i := 0; i < len(name); i++ {
+		if !unreserved(name[i]) {
+			u := url.URL{Path: name}
+			return u.String()
+		}
+	}
+	return name
+}
+
+// unreserved reports whether c never needs escaping in a URL path.
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~' || c == '/'
+}
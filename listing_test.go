@@ -0,0 +1,291 @@
+package httpfs_test
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+	"github.com/absfs/memfs"
+)
+
+// fileInfo is a static os.FileInfo for mocks.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() os.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() interface{}   { return nil }
+
+// symlinkFS presents the files named in links as symbolic links. The link
+// files exist in the wrapped filer as regular files standing in for their
+// followed targets.
+type symlinkFS struct {
+	absfs.Filer
+	links map[string]string
+}
+
+func (fs *symlinkFS) Lstat(name string) (os.FileInfo, error) {
+	if _, ok := fs.links[name]; ok {
+		return &fileInfo{name: name[strings.LastIndex(name, "/")+1:], mode: os.ModeSymlink | 0777}, nil
+	}
+	return fs.Filer.Stat(name)
+}
+
+func (fs *symlinkFS) Readlink(name string) (string, error) {
+	target, ok := fs.links[name]
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return target, nil
+}
+
+func (fs *symlinkFS) Symlink(oldname, newname string) error {
+	fs.links[newname] = oldname
+	return nil
+}
+
+func TestLstatListings(t *testing.T) {
+	sfs := &symlinkFS{
+		Filer: newMemFS(t, map[string]string{
+			"/dir/file.txt": "regular",
+			"/dir/link.txt": "followed",
+		}),
+		links: map[string]string{"/dir/link.txt": "/target.txt"},
+	}
+
+	list := func(fs *httpfs.Httpfs) string {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/dir/", nil))
+		return w.Body.String()
+	}
+
+	body := list(httpfs.New(sfs, httpfs.WithLstatListings(true)))
+	if !strings.Contains(body, `<a href="link.txt">link.txt</a> -&gt; /target.txt`) {
+		t.Errorf("symlink not marked in listing:\n%s", body)
+	}
+	if !strings.Contains(body, "<a href=\"file.txt\">file.txt</a>\n") {
+		t.Errorf("regular file marked as link:\n%s", body)
+	}
+
+	body = list(httpfs.New(sfs))
+	if strings.Contains(body, "-&gt;") {
+		t.Errorf("symlink marked without WithLstatListings:\n%s", body)
+	}
+}
+
+func TestFileSystemName(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/docs/a.txt": "a"}),
+		httpfs.WithFileSystemName("Shared <Files>"))
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"<title>Shared &lt;Files&gt; — /docs/</title>",
+		"<h1>Shared &lt;Files&gt; — /docs/</h1>",
+		`<a href="a.txt">a.txt</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestListingAuthorizer(t *testing.T) {
+	files := map[string]string{"/docs/a.txt": "a"}
+	authorize := func(r *http.Request, dir string) bool {
+		return r.Header.Get("Authorization") == "letmein"
+	}
+
+	get := func(fs *httpfs.Httpfs, name, auth string) int {
+		req := httptest.NewRequest("GET", name, nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	fs := httpfs.New(newMemFS(t, files), httpfs.WithListingAuthorizer(authorize))
+	if code := get(fs, "/docs/", ""); code != http.StatusForbidden {
+		t.Errorf("unauthorized listing: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := get(fs, "/docs/a.txt", ""); code != http.StatusOK {
+		t.Errorf("unauthorized file: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := get(fs, "/docs/", "letmein"); code != http.StatusOK {
+		t.Errorf("authorized listing: status = %d, want %d", code, http.StatusOK)
+	}
+
+	fs = httpfs.New(newMemFS(t, files), httpfs.WithListingAuthorizer(authorize), httpfs.WithAuthorizeFiles(true))
+	if code := get(fs, "/docs/a.txt", ""); code != http.StatusForbidden {
+		t.Errorf("gated file: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := get(fs, "/docs/a.txt", "letmein"); code != http.StatusOK {
+		t.Errorf("authorized gated file: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestListTimeFormat(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{"/docs/a.txt": "a"})
+	modTime := time.Date(2024, time.March, 1, 23, 30, 0, 0, time.UTC)
+	err := mfs.Chtimes("/docs/a.txt", modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := httpfs.New(mfs,
+		httpfs.WithListTimeFormat("02 Jan 2006 15:04 MST"),
+		httpfs.WithListTimeLocation(time.FixedZone("JST", 9*60*60)))
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	want := `<a href="a.txt">a.txt</a>  02 Mar 2024 08:30 JST`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("listing missing %q:\n%s", want, w.Body.String())
+	}
+}
+
+func TestListingOutput(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/dir/b.txt":          "b",
+		"/dir/a & <b>.txt":    "a",
+		"/dir/what?#.txt":     "w",
+		"/dir/sub/c.txt":      "c",
+		"/dir/colon:name.txt": "c",
+	}), httpfs.WithFileSystemName("Files"))
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/dir/", nil))
+	want := `<!doctype html>
+<meta charset="utf-8">
+<title>Files — /dir/</title>
+<h1>Files — /dir/</h1>
+<pre>
+<a href="a%20&amp;%20%3Cb%3E.txt">a &amp; &lt;b&gt;.txt</a>
+<a href="b.txt">b.txt</a>
+<a href="./colon:name.txt">colon:name.txt</a>
+<a href="sub/">sub/</a>
+<a href="what%3F%23.txt">what?#.txt</a>
+</pre>
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("listing =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func BenchmarkListing(b *testing.B) {
+	mfs, err := memfs.NewFS()
+	if err != nil {
+		b.Fatal(err)
+	}
+	fs := httpfs.New(mfs, httpfs.WithFileSystemName("bench"))
+	err = fs.Mkdir("/dir", 0755)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		f, err := fs.OpenFile(fmt.Sprintf("/dir/file-%04d.txt", i), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+
+	req := httptest.NewRequest("GET", "/dir/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestHideDotfilesListing(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/dir/.env":      "secret",
+		"/dir/.git/HEAD": "ref",
+		"/dir/app.js":    "app",
+	}), httpfs.WithHideDotfiles(true))
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/dir/", nil))
+	if body := w.Body.String(); strings.Contains(body, ".env") || strings.Contains(body, ".git") || !strings.Contains(body, "app.js") {
+		t.Errorf("listing:\n%s", body)
+	}
+}
+
+func TestDirectoryListingDisabled(t *testing.T) {
+	files := map[string]string{
+		"/site/index.html": "home",
+		"/files/a.txt":     "a",
+	}
+	for _, enabled := range []bool{true, false} {
+		fs := httpfs.New(newMemFS(t, files), httpfs.WithDirectoryListing(enabled))
+
+		for _, h := range []http.Handler{fs, http.FileServer(fs)} {
+			get := func(target string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+				return w
+			}
+
+			w := get("/files/")
+			switch {
+			case enabled && (w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "a.txt")):
+				t.Errorf("listing enabled: GET /files/ = %d\n%s", w.Code, w.Body)
+			case !enabled && w.Code != http.StatusForbidden:
+				t.Errorf("listing disabled: GET /files/ = %d, want 403", w.Code)
+			}
+			if w := get("/site/"); w.Code != http.StatusOK || w.Body.String() != "home" {
+				t.Errorf("listing enabled %v: GET /site/ = %d %q, want the index page", enabled, w.Code, w.Body)
+			}
+			if w := get("/files/a.txt"); w.Code != http.StatusOK {
+				t.Errorf("listing enabled %v: GET /files/a.txt = %d", enabled, w.Code)
+			}
+		}
+
+		_, err := fs.Open("/files")
+		if enabled == (err != nil) || (!enabled && !os.IsPermission(err)) {
+			t.Errorf("listing enabled %v: Open(/files) = %v", enabled, err)
+		}
+	}
+}
+
+func TestListingTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("listing").Parse(
+		`<h1>Index of {{.Path}}</h1>{{range .Entries}}<li><a href="{{.URL}}">{{.Name}}</a> {{.Size}}{{if .IsDir}} dir{{end}}{{end}}`))
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/docs/notes & plans.txt": "12345",
+		"/docs/img/logo.png":      "",
+	}), httpfs.WithListingTemplate(tmpl))
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	want := `<h1>Index of /docs/</h1><li><a href="img/">img</a> 0 dir<li><a href="notes%20&amp;%20plans.txt">notes &amp; plans.txt</a> 5`
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("listing = %d %q, want %q", w.Code, w.Body.String(), want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	broken := template.Must(template.New("listing").Parse(`{{.Missing}}`))
+	fs = httpfs.New(newMemFS(t, map[string]string{"/docs/a.txt": ""}), httpfs.WithListingTemplate(broken))
+	w = httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("failing template status = %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package httpfs
+
+import (
+	"hash/fnv"
+	"path"
+	"sync"
+)
+
+// lockShards is the number of locks paths are spread over by WithLocking.
+const lockShards = 64
+
+// WithLocking serializes operations on the same path, for filers that are not
+// safe for concurrent use. Opening a file for writing, Mkdir, Remove, Rename
+// and changes to file metadata on a path exclude all other operations on it,
+// while reads such as Stat and opening for reading share. Locks are held for
+// the duration of each call to the underlying filer, not while an open file
+// is used, and paths are spread over a fixed number of locks, so operations
+// on unrelated paths occasionally wait for each other too.
+func WithLocking(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.locks = nil
+		if enabled {
+			filer.locks = make([]sync.RWMutex, lockShards)
+		}
+	}
+}
+
+func unlockNothing() {}
+
+// lock locks the path name of the underlying filer, exclusively if write is
+// set, and returns the function unlocking it.
+func (filer *Httpfs) lock(name string, write bool) (unlock func()) {
+	if filer.locks == nil {
+		return unlockNothing
+	}
+	m := &filer.locks[lockShard(name)]
+	if write {
+		m.Lock()
+		return m.Unlock
+	}
+	m.RLock()
+	return m.RUnlock
+}
+
+// lock2 exclusively locks the paths a and b of the underlying filer, in shard
+// order so that concurrent calls cannot deadlock, and returns the function
+// unlocking them.
+func (filer *Httpfs) lock2(a, b string) (unlock func()) {
+	if filer.locks == nil {
+		return unlockNothing
+	}
+	i, j := lockShard(a), lockShard(b)
+	if i == j {
+		return filer.lock(a, true)
+	}
+	if i > j {
+		i, j = j, i
+	}
+	filer.locks[i].Lock()
+	filer.locks[j].Lock()
+	return func() {
+		filer.locks[j].Unlock()
+		filer.locks[i].Unlock()
+	}
+}
+
+func lockShard(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(path.Clean("/" + name)))
+	return h.Sum32() % lockShards
+}
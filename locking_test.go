@@ -0,0 +1,83 @@
+package httpfs_test
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// unsyncFS keeps an unsynchronized per-path operation count, standing in for
+// a filer that is not safe for concurrent use. Writes update the count and
+// reads load it, so unserialized access trips the race detector.
+type unsyncFS struct {
+	absfs.Filer
+	writes map[string]*int
+}
+
+func (fs *unsyncFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if n := fs.writes[name]; n != nil {
+		if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+			*n++
+		} else {
+			_ = *n
+		}
+	}
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func (fs *unsyncFS) Stat(name string) (os.FileInfo, error) {
+	if n := fs.writes[name]; n != nil {
+		_ = *n
+	}
+	return fs.Filer.Stat(name)
+}
+
+func TestLocking(t *testing.T) {
+	ufs := &unsyncFS{
+		Filer:  newMemFS(t, map[string]string{"/shared.txt": ""}),
+		writes: map[string]*int{"/shared.txt": new(int)},
+	}
+	fs := httpfs.New(ufs, httpfs.WithLocking(true))
+
+	const writers, iterations = 4, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				f, err := fs.OpenFile("/shared.txt", os.O_WRONLY|os.O_TRUNC, 0644)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				f.Write([]byte("data"))
+				f.Close()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < iterations; j++ {
+			if _, err := fs.Stat("/shared.txt"); err != nil {
+				t.Error(err)
+				return
+			}
+			f, err := fs.Open("/shared.txt")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			f.Close()
+		}
+	}()
+	wg.Wait()
+
+	if n := *ufs.writes["/shared.txt"]; n != writers*iterations {
+		t.Errorf("writes = %d, want %d", n, writers*iterations)
+	}
+}
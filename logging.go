@@ -0,0 +1,86 @@
+package httpfs
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LoggingHandler wraps next, typically an Httpfs or an http.FileServer over
+// one, to log each request it serves to logger, or to slog.Default if logger
+// is nil. Each record holds the method, the request path, the response
+// status, the number of body bytes written and the time taken. If next is an
+// *Httpfs, records also hold the file, as Httpfs.LoggingHandler logs it.
+func LoggingHandler(next http.Handler, logger *slog.Logger) http.Handler {
+	filer, _ := next.(*Httpfs)
+	return filer.loggingHandler(next, logger)
+}
+
+// LoggingHandler wraps next, a handler serving the files of filer such as
+// http.FileServer(filer), to log each request to logger as the package
+// function LoggingHandler does. Records also hold the file, the path in the
+// underlying filer that the request path maps to once the prefix, rewrite
+// and allowed prefixes of filer are applied, before index pages and clean
+// URLs are looked up. It is left out for paths that map to no file.
+func (filer *Httpfs) LoggingHandler(next http.Handler, logger *slog.Logger) http.Handler {
+	return filer.loggingHandler(next, logger)
+}
+
+// loggingHandler returns the handler of LoggingHandler, logging files as
+// filer resolves them unless filer is nil.
+func (filer *Httpfs) loggingHandler(next http.Handler, logger *slog.Logger) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &loggingWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		}
+		if filer != nil {
+			if p, err := filer.resolve("open", r.URL.Path); err == nil {
+				attrs = append(attrs, slog.String("file", p))
+			}
+		}
+		attrs = append(attrs,
+			slog.Int("status", lw.status),
+			slog.Int64("bytes", lw.bytes),
+			slog.Duration("duration", time.Since(start)),
+		)
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "serve", attrs...)
+	})
+}
+
+// loggingWriter records the status and body size of a response.
+type loggingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *loggingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *loggingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (w *loggingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
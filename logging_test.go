@@ -0,0 +1,60 @@
+package httpfs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+// logRecord is a record logged by LoggingHandler.
+type logRecord struct {
+	Msg    string
+	Method string
+	Path   string
+	File   *string
+	Status int
+	Bytes  int64
+}
+
+func TestLoggingHandler(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/docs/a.txt": "hello"}), httpfs.WithStripPrefix("/static"))
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	serve := func(h http.Handler, target string) logRecord {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		var record logRecord
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("log %q: %v", buf.String(), err)
+		}
+		return record
+	}
+
+	record := serve(fs.LoggingHandler(http.FileServer(fs), logger), "/static/docs/../docs/a.txt")
+	if record.Method != "GET" || record.File == nil || *record.File != "/docs/a.txt" || record.Status != http.StatusOK {
+		t.Errorf("record = %+v", record)
+	}
+	if record.Bytes != int64(len("hello")) {
+		t.Errorf("bytes = %d, want %d", record.Bytes, len("hello"))
+	}
+
+	record = serve(httpfs.LoggingHandler(fs, logger), "/static/docs/a.txt")
+	if record.File == nil || *record.File != "/docs/a.txt" {
+		t.Errorf("record for an Httpfs = %+v", record)
+	}
+	record = serve(httpfs.LoggingHandler(fs, logger), "/other/a.txt")
+	if record.File != nil || record.Status != http.StatusNotFound {
+		t.Errorf("record for a path outside the prefix = %+v", record)
+	}
+	record = serve(httpfs.LoggingHandler(http.NotFoundHandler(), logger), "/static/docs/a.txt")
+	if record.File != nil {
+		t.Errorf("record for another handler = %+v", record)
+	}
+}
@@ -0,0 +1,113 @@
+package httpfs
+
+import "time"
+
+// op identifies a filesystem operation in metrics.
+type op int
+
+const (
+	opOpen op = iota
+	opStat
+	opMkdir
+	opRemove
+	opChmod
+	opChtimes
+	opChown
+	opRename
+	opTruncate
+	numOps
+)
+
+var opNames = [numOps]string{
+	opOpen:     "open",
+	opStat:     "stat",
+	opMkdir:    "mkdir",
+	opRemove:   "remove",
+	opChmod:    "chmod",
+	opChtimes:  "chtimes",
+	opChown:    "chown",
+	opRename:   "rename",
+	opTruncate: "truncate",
+}
+
+// WithSlowOpThreshold counts the operations on the underlying filer that take
+// longer than d, reported by Stats.
+func WithSlowOpThreshold(d time.Duration) Option {
+	return func(filer *Httpfs) {
+		filer.slowThreshold = d
+	}
+}
+
+// Stats is a snapshot of the operation counters of an Httpfs.
+type Stats struct {
+	// SlowOps counts, by operation name, the operations that took longer
+	// than the threshold set with WithSlowOpThreshold.
+	SlowOps map[string]int64
+}
+
+// Stats returns a snapshot of the operation counters.
+func (filer *Httpfs) Stats() Stats {
+	stats := Stats{SlowOps: make(map[string]int64)}
+	for o := op(0); o < numOps; o++ {
+		if n := filer.slowOps[o].Load(); n > 0 {
+			stats.SlowOps[opNames[o]] = n
+		}
+	}
+	return stats
+}
+
+// An Observer is told of each operation on the underlying filer, with the
+// name it was given, the error it returned and how long it took, for example
+// to feed monitoring counters and histograms. Its methods may be called
+// concurrently.
+type Observer interface {
+	OnOpen(name string, err error, dur time.Duration)
+	OnStat(name string, err error, dur time.Duration)
+	OnRemove(name string, err error, dur time.Duration)
+	// OnWrite is called for the other operations modifying the filesystem:
+	// op is one of "mkdir", "chmod", "chtimes", "chown", "truncate" or
+	// "rename", which is reported with the old name.
+	OnWrite(op, name string, err error, dur time.Duration)
+}
+
+// WithObserver sets an Observer told of each operation on the underlying
+// filer.
+func WithObserver(observer Observer) Option {
+	return func(filer *Httpfs) {
+		filer.observer = observer
+	}
+}
+
+// startOp returns the start time of an operation, or the zero time if
+// operations are not being timed.
+func (filer *Httpfs) startOp() time.Time {
+	if filer.slowThreshold <= 0 && filer.observer == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// endOp records the end of the operation o on the file name begun at start,
+// which failed with err if not nil.
+func (filer *Httpfs) endOp(o op, name string, start time.Time, err error) {
+	if start.IsZero() {
+		return
+	}
+	dur := time.Since(start)
+	if filer.slowThreshold > 0 && dur > filer.slowThreshold {
+		filer.slowOps[o].Add(1)
+	}
+	if filer.observer == nil {
+		return
+	}
+	switch o {
+	case opOpen:
+		filer.observer.OnOpen(name, err, dur)
+	case opStat:
+		filer.observer.OnStat(name, err, dur)
+	case opRemove:
+		filer.observer.OnRemove(name, err, dur)
+	default:
+		filer.observer.OnWrite(opNames[o], name, err, dur)
+	}
+}
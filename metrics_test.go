@@ -0,0 +1,108 @@
+package httpfs_test
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+// countingObserver counts the operations it is told of, by operation and
+// outcome.
+type countingObserver struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (o *countingObserver) record(op string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.calls == nil {
+		o.calls = make(map[string]int)
+	}
+	if err != nil {
+		op += " failed"
+	}
+	o.calls[op]++
+}
+
+func (o *countingObserver) OnOpen(name string, err error, dur time.Duration) {
+	o.record("open", err)
+}
+
+func (o *countingObserver) OnStat(name string, err error, dur time.Duration) {
+	o.record("stat", err)
+}
+
+func (o *countingObserver) OnRemove(name string, err error, dur time.Duration) {
+	o.record("remove", err)
+}
+
+func (o *countingObserver) OnWrite(op, name string, err error, dur time.Duration) {
+	o.record(op, err)
+}
+
+func TestObserver(t *testing.T) {
+	var o countingObserver
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a.txt": "a"}), httpfs.WithObserver(&o))
+
+	if _, err := fs.Stat("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	fs.Stat("/missing.txt")
+	if err := fs.Mkdir("/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile("/dir/b.txt", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := fs.Chmod("/dir/b.txt", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{
+		"stat":        1,
+		"stat failed": 1,
+		"mkdir":       1,
+		"open":        1,
+		"chmod":       1,
+		"remove":      1,
+	}
+	if !reflect.DeepEqual(o.calls, want) {
+		t.Errorf("observed %v, want %v", o.calls, want)
+	}
+}
+
+func TestSlowOpThreshold(t *testing.T) {
+	sfs := &slowStatFS{
+		Filer: newMemFS(t, map[string]string{"/a.txt": "a"}),
+		delay: 20 * time.Millisecond,
+	}
+	fs := httpfs.New(sfs, httpfs.WithSlowOpThreshold(5*time.Millisecond))
+
+	_, err := fs.Stat("/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile("/a.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	slow := fs.Stats().SlowOps
+	if slow["stat"] != 1 {
+		t.Errorf("slow stats = %d, want 1", slow["stat"])
+	}
+	if slow["open"] != 0 {
+		t.Errorf("slow opens = %d, want 0", slow["open"])
+	}
+}
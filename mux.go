@@ -0,0 +1,57 @@
+package httpfs
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/absfs/absfs"
+)
+
+// A Mux serves several filers under different path prefixes as a single
+// http.FileSystem, for use with http.FileServer. The zero value is an empty
+// Mux ready to use.
+type Mux struct {
+	mu     sync.RWMutex
+	mounts map[string]*Httpfs
+}
+
+// Mount serves filer, configured with opts, under prefix, replacing any filer
+// already mounted there. Names under prefix are passed to filer with prefix
+// removed. Where prefixes overlap, the longest one matching a name wins.
+func (m *Mux) Mount(prefix string, filer absfs.Filer, opts ...Option) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mounts == nil {
+		m.mounts = make(map[string]*Httpfs)
+	}
+	m.mounts[path.Clean("/"+prefix)] = New(filer, opts...)
+}
+
+// Open opens the named file in the filer mounted under the longest prefix
+// of name. Names under no prefix do not exist.
+func (m *Mux) Open(name string) (http.File, error) {
+	filer, rest := m.match(path.Clean("/" + name))
+	if filer == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return filer.Open(rest)
+}
+
+// match returns the filer mounted under the longest prefix of name, and name
+// relative to it.
+func (m *Mux) match(name string) (*Httpfs, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for prefix := name; ; prefix = path.Dir(prefix) {
+		if filer, ok := m.mounts[prefix]; ok {
+			return filer, "/" + strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+		}
+		if prefix == "/" {
+			return nil, ""
+		}
+	}
+}
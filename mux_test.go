@@ -0,0 +1,55 @@
+package httpfs_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestMux(t *testing.T) {
+	var mux httpfs.Mux
+	mux.Mount("/docs", newMemFS(t, map[string]string{
+		"/a.txt":      "docs a",
+		"/api/b.txt":  "docs api b",
+		"/secret.txt": "docs secret",
+	}))
+	mux.Mount("/docs/api/", newMemFS(t, map[string]string{
+		"/b.txt": "api b",
+	}))
+	mux.Mount("/assets", newMemFS(t, map[string]string{
+		"/a.txt": "assets a",
+	}))
+
+	srv := httptest.NewServer(http.FileServer(&mux))
+	defer srv.Close()
+
+	for target, want := range map[string]string{
+		"/docs/a.txt":     "docs a",
+		"/docs/api/b.txt": "api b",
+		"/assets/a.txt":   "assets a",
+	} {
+		resp, err := http.Get(srv.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("GET %s = %d %q, want %q", target, resp.StatusCode, body, want)
+		}
+	}
+
+	for _, target := range []string{
+		"/assets/secret.txt",
+		"/assets/../docs/../assets/api/b.txt",
+		"/other/a.txt",
+		"/docsa.txt",
+	} {
+		if _, err := mux.Open(target); err == nil {
+			t.Errorf("Open(%q) succeeded", target)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package httpfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// whiteoutPrefix begins the names of the files an overlay creates in its
+// upper layer to hide the file of the same name, less the prefix, in its
+// lower layer.
+const whiteoutPrefix = ".wh."
+
+// opaqueMarker is the name of the file an overlay creates in an upper layer
+// directory to hide the contents of the lower layer directory it replaces.
+const opaqueMarker = whiteoutPrefix + whiteoutPrefix + ".opq"
+
+// NewOverlay returns an Httpfs over the union of upper and lower. Reads find
+// files in upper first and then in lower. Writes always go to upper, copying
+// a file up from lower first if needed, so lower is never modified and may
+// be read-only. Directory listings merge both layers, upper entries shadowing
+// lower entries of the same name. Removing a file that exists in lower
+// records a whiteout in upper hiding it. Names beginning with ".wh." are
+// reserved for whiteouts: they cannot be created or removed.
+func NewOverlay(upper, lower absfs.Filer, opts ...Option) *Httpfs {
+	return New(&overlay{upper: upper, lower: lower}, opts...)
+}
+
+// overlay is the union filer returned by NewOverlay.
+type overlay struct {
+	upper, lower absfs.Filer
+}
+
+// whiteout returns the name of the whiteout hiding name.
+func whiteout(name string) string {
+	dir, base := path.Split(path.Clean("/" + name))
+	return path.Join(dir, whiteoutPrefix+base)
+}
+
+func exists(fs absfs.Filer, name string) bool {
+	_, err := fs.Stat(name)
+	return err == nil
+}
+
+// reserved returns an error for op if name is reserved for a whiteout or an
+// opaque marker, which may not be created or removed through the overlay.
+func reserved(op, name string) error {
+	if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+		return &os.PathError{Op: op, Path: name, Err: syscall.EINVAL}
+	}
+	return nil
+}
+
+// whitedOut reports whether name in the lower layer is hidden by a whiteout
+// or an opaque directory in the upper layer, for it or any of its parents.
+func (o *overlay) whitedOut(name string) bool {
+	for name = path.Clean("/" + name); name != "/"; name = path.Dir(name) {
+		if exists(o.upper, whiteout(name)) || exists(o.upper, path.Join(path.Dir(name), opaqueMarker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// layer returns the layer holding the visible version of name, and its info.
+func (o *overlay) layer(op, name string) (absfs.Filer, os.FileInfo, error) {
+	if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+		return nil, nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	info, err := o.upper.Stat(name)
+	if err == nil {
+		return o.upper, info, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	if o.whitedOut(name) {
+		return nil, nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	info, err = o.lower.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.lower, info, nil
+}
+
+// copyUp copies name, whose info in the lower layer is info, to the upper
+// layer along with any missing parents. File contents are copied only if
+// withData is set.
+func (o *overlay) copyUp(name string, info os.FileInfo, withData bool) error {
+	err := o.copyUpParent(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = o.upper.Mkdir(name, info.Mode().Perm())
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+		return o.upper.Chtimes(name, info.ModTime(), info.ModTime())
+	}
+
+	dst, err := o.upper.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if withData {
+		var src absfs.File
+		src, err = o.lower.OpenFile(name, os.O_RDONLY, 0)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+			src.Close()
+		}
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		o.upper.Remove(name)
+		return err
+	}
+	return o.upper.Chtimes(name, info.ModTime(), info.ModTime())
+}
+
+// copyUpParent makes sure the parent directory of name exists in the upper
+// layer, copying it up from the lower layer if needed.
+func (o *overlay) copyUpParent(name string) error {
+	dir := path.Dir(path.Clean("/" + name))
+	l, info, err := o.layer("open", dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "open", Path: dir, Err: syscall.ENOTDIR}
+	}
+	if l == o.upper {
+		return nil
+	}
+	return o.copyUp(dir, info, false)
+}
+
+func (o *overlay) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if isWrite(flag) {
+		if err := reserved("open", name); err != nil {
+			return nil, err
+		}
+	}
+	l, info, err := o.layer("open", name)
+	if !isWrite(flag) {
+		if err != nil {
+			return nil, err
+		}
+		f, err := l.OpenFile(name, flag, perm)
+		if err != nil || !info.IsDir() {
+			return f, err
+		}
+		return &overlayDir{File: f, o: o, name: name}, nil
+	}
+
+	switch {
+	case err == nil && l == o.lower:
+		err = o.copyUp(name, info, flag&os.O_TRUNC == 0)
+	case os.IsNotExist(err) && flag&os.O_CREATE != 0:
+		err = o.copyUpParent(name)
+		o.upper.Remove(whiteout(name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o.upper.OpenFile(name, flag, perm)
+}
+
+func (o *overlay) Mkdir(name string, perm os.FileMode) error {
+	if err := reserved("mkdir", name); err != nil {
+		return err
+	}
+	_, _, err := o.layer("mkdir", name)
+	if err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	err = o.copyUpParent(name)
+	if err != nil {
+		return err
+	}
+
+	o.upper.Remove(whiteout(name))
+	err = o.upper.Mkdir(name, perm)
+	if err != nil || !exists(o.lower, name) {
+		return err
+	}
+	// The directory replaces a removed lower directory, whose contents must
+	// stay hidden.
+	f, err := o.upper.OpenFile(path.Join(name, opaqueMarker), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (o *overlay) Remove(name string) error {
+	if err := reserved("remove", name); err != nil {
+		return err
+	}
+	_, info, err := o.layer("remove", name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		infos, err := o.readDir(name)
+		if err != nil {
+			return err
+		}
+		if len(infos) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	inLower := !o.whitedOut(name) && exists(o.lower, name)
+	if exists(o.upper, name) {
+		if info.IsDir() {
+			err = o.removeMarkers(name)
+			if err != nil {
+				return err
+			}
+		}
+		err = o.upper.Remove(name)
+		if err != nil {
+			return err
+		}
+	}
+	if !inLower {
+		return nil
+	}
+	err = o.copyUpParent(name)
+	if err != nil {
+		return err
+	}
+	f, err := o.upper.OpenFile(whiteout(name), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// removeMarkers removes the whiteouts and opaque marker in the upper layer
+// directory dir.
+func (o *overlay) removeMarkers(dir string) error {
+	infos, err := readdirAll(o.upper, dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), whiteoutPrefix) {
+			err = o.upper.Remove(path.Join(dir, info.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *overlay) Stat(name string) (os.FileInfo, error) {
+	_, info, err := o.layer("stat", name)
+	return info, err
+}
+
+// upperFor copies name up to the upper layer, if it is only in the lower
+// layer, so that it can be modified.
+func (o *overlay) upperFor(op, name string) error {
+	l, info, err := o.layer(op, name)
+	if err != nil || l == o.upper {
+		return err
+	}
+	return o.copyUp(name, info, true)
+}
+
+func (o *overlay) Chmod(name string, mode os.FileMode) error {
+	if err := o.upperFor("chmod", name); err != nil {
+		return err
+	}
+	return o.upper.Chmod(name, mode)
+}
+
+func (o *overlay) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := o.upperFor("chtimes", name); err != nil {
+		return err
+	}
+	return o.upper.Chtimes(name, atime, mtime)
+}
+
+func (o *overlay) Chown(name string, uid, gid int) error {
+	if err := o.upperFor("chown", name); err != nil {
+		return err
+	}
+	return o.upper.Chown(name, uid, gid)
+}
+
+// readDir returns the merged entries of the directory name sorted by name:
+// the entries of the upper layer directory, less whiteouts, and those of the
+// lower layer directory that are neither shadowed nor whited out.
+func (o *overlay) readDir(name string) ([]os.FileInfo, error) {
+	var infos []os.FileInfo
+	seen := make(map[string]bool)
+	opaque := false
+
+	if exists(o.upper, name) {
+		upper, err := readdirAll(o.upper, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range upper {
+			switch n := info.Name(); {
+			case n == opaqueMarker:
+				opaque = true
+			case strings.HasPrefix(n, whiteoutPrefix):
+				seen[n[len(whiteoutPrefix):]] = true
+			default:
+				seen[n] = true
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	if !opaque && !o.whitedOut(name) && exists(o.lower, name) {
+		lower, err := readdirAll(o.lower, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range lower {
+			if !seen[info.Name()] {
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// readdirAll returns all entries of the directory name in fs.
+func readdirAll(fs absfs.Filer, name string) ([]os.FileInfo, error) {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	infos, err := f.Readdir(0)
+	if err == io.EOF {
+		err = nil
+	}
+	return infos, err
+}
+
+// overlayDir is a directory opened in an overlay, whose Readdir merges the
+// entries of both layers.
+type overlayDir struct {
+	absfs.File
+	o    *overlay
+	name string
+
+	infos []os.FileInfo
+	read  bool
+}
+
+func (d *overlayDir) Readdir(n int) ([]os.FileInfo, error) {
+	if !d.read {
+		infos, err := d.o.readDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.infos, d.read = infos, true
+	}
+	if n <= 0 {
+		infos := d.infos
+		d.infos = nil
+		return infos, nil
+	}
+	if len(d.infos) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.infos) {
+		n = len(d.infos)
+	}
+	infos := d.infos[:n]
+	d.infos = d.infos[n:]
+	return infos, nil
+}
+
+func (d *overlayDir) Readdirnames(n int) ([]string, error) {
+	infos, err := d.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
@@ -0,0 +1,210 @@
+package httpfs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func newOverlay(t *testing.T) (*httpfs.Httpfs, *httpfs.Httpfs, *httpfs.Httpfs) {
+	lower := httpfs.New(newMemFS(t, map[string]string{
+		"/site/index.html":   "lower index",
+		"/site/style.css":    "lower style",
+		"/site/old.html":     "old",
+		"/site/img/logo.png": "logo",
+	}))
+	upper := httpfs.New(newMemFS(t, map[string]string{
+		"/site/style.css": "upper style",
+		"/site/new.html":  "new",
+	}))
+	return httpfs.NewOverlay(upper, lower), upper, lower
+}
+
+func TestOverlayReads(t *testing.T) {
+	fs, _, _ := newOverlay(t)
+
+	for name, want := range map[string]string{
+		"/site/index.html":   "lower index",
+		"/site/style.css":    "upper style",
+		"/site/new.html":     "new",
+		"/site/img/logo.png": "logo",
+	} {
+		if got := readFile(t, fs, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := fs.Stat("/site/missing.html"); !os.IsNotExist(err) {
+		t.Errorf("Stat of a missing file: %v", err)
+	}
+}
+
+func TestOverlayWrites(t *testing.T) {
+	fs, upper, lower := newOverlay(t)
+
+	f, err := fs.OpenFile("/site/index.html", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(" edited"))
+	f.Close()
+	if got := readFile(t, fs, "/site/index.html"); got != "lower index edited" {
+		t.Errorf("edited file = %q", got)
+	}
+	if got := readFile(t, lower, "/site/index.html"); got != "lower index" {
+		t.Errorf("lower layer modified: %q", got)
+	}
+
+	if err := fs.MkdirAll("/site/img/icons", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err = fs.OpenFile("/site/img/icons/a.svg", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := upper.Stat("/site/img/icons/a.svg"); err != nil {
+		t.Errorf("new file not in upper layer: %v", err)
+	}
+	if _, err := lower.Stat("/site/img/icons"); !os.IsNotExist(err) {
+		t.Errorf("lower layer modified: %v", err)
+	}
+
+	if err := fs.Remove("/site/old.html"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/site/old.html"); !os.IsNotExist(err) {
+		t.Errorf("removed lower file still visible: %v", err)
+	}
+	if _, err := lower.Stat("/site/old.html"); err != nil {
+		t.Errorf("lower file removed: %v", err)
+	}
+
+	if err := fs.RemoveAll("/site/img"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/site/img/logo.png"); !os.IsNotExist(err) {
+		t.Errorf("removed lower directory still visible: %v", err)
+	}
+	if err := fs.Mkdir("/site/img", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := fs.ReadDir("/site/img"); err != nil || len(entries) != 0 {
+		t.Errorf("recreated directory = %v, %v; want empty", entries, err)
+	}
+}
+
+func TestOverlayListing(t *testing.T) {
+	fs, _, _ := newOverlay(t)
+	if err := fs.Remove("/site/old.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := fs.ReadDir("/site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"img", "index.html", "new.html", "style.css"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir = %q, want %q", names, want)
+	}
+	if info, _ := entries[3].Info(); info.Size() != int64(len("upper style")) {
+		t.Errorf("style.css size = %d, want the upper file's", info.Size())
+	}
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/site/img/", nil))
+	if body := w.Body.String(); !strings.Contains(body, "logo.png") {
+		t.Errorf("listing:\n%s", body)
+	}
+	w = httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/site/", nil))
+	if body := w.Body.String(); body != "lower index" {
+		t.Errorf("index = %q", body)
+	}
+}
+
+func TestOverlayMergedReaddir(t *testing.T) {
+	fs, _, _ := newOverlay(t)
+	for _, name := range []string{"/site/old.html", "/site/index.html"} {
+		if err := fs.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"img", "new.html", "style.css"}
+
+	f, err := fs.Open("/site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	for {
+		infos, err := f.Readdir(2)
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		if err != nil {
+			break
+		}
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Readdir in pages = %q, want %q", names, want)
+	}
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/site/", nil))
+	body := w.Body.String()
+	for _, name := range want {
+		if !strings.Contains(body, name) {
+			t.Errorf("listing lacks %s:\n%s", name, body)
+		}
+	}
+	for _, name := range []string{"old.html", "index.html", ".wh."} {
+		if strings.Contains(body, name) {
+			t.Errorf("listing shows %s:\n%s", name, body)
+		}
+	}
+}
+
+func TestOverlayReservedNames(t *testing.T) {
+	fs, upper, _ := newOverlay(t)
+	if err := fs.Remove("/site/old.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("PUT", "/site/.wh.index.html", strings.NewReader("")))
+	if w.Code == http.StatusCreated || w.Code == http.StatusNoContent {
+		t.Errorf("PUT of a whiteout: status %d", w.Code)
+	}
+	if _, err := fs.OpenFile("/site/.wh.style.css", os.O_CREATE|os.O_WRONLY, 0644); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("creating a whiteout: err = %v, want %v", err, syscall.EINVAL)
+	}
+	if err := fs.Mkdir("/site/.wh..wh..opq", 0755); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("creating an opaque marker: err = %v, want %v", err, syscall.EINVAL)
+	}
+	if err := fs.Remove("/site/.wh.old.html"); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("removing a whiteout: err = %v, want %v", err, syscall.EINVAL)
+	}
+
+	if got := readFile(t, fs, "/site/index.html"); got != "lower index" {
+		t.Errorf("/site/index.html = %q", got)
+	}
+	if _, err := fs.Stat("/site/old.html"); !os.IsNotExist(err) {
+		t.Errorf("whited out file visible: %v", err)
+	}
+	if _, err := upper.Stat("/site/.wh.old.html"); err != nil {
+		t.Errorf("whiteout removed: %v", err)
+	}
+}
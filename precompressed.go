@@ -0,0 +1,66 @@
+package httpfs
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+)
+
+// WithPrecompressedSidecars serves the gzip compressed sidecar <name>.gz, if
+// there is one, in place of the file name to clients that accept gzip. The
+// response keeps the content type of name and is sent with
+// Content-Encoding: gzip, saving the cost of compressing on the fly.
+func WithPrecompressedSidecars(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.sidecars = enabled
+	}
+}
+
+// openSidecar opens the gzip sidecar of the file name, already opened as f,
+// if r accepts gzip and there is one. It sets the headers for serving the
+// sidecar in place of name and returns it along with its FileInfo, or returns
+// a nil file if name is to be served as is.
+func (filer *Httpfs) openSidecar(w http.ResponseWriter, r *http.Request, name string, f http.File) (http.File, os.FileInfo) {
+	addVary(w.Header(), "Accept-Encoding")
+	if !acceptsGzip(r) {
+		return nil, nil
+	}
+	gz, err := filer.OpenContext(r.Context(), name+".gz")
+	if err != nil {
+		return nil, nil
+	}
+	info, err := gz.Stat()
+	if err != nil || info.IsDir() {
+		gz.Close()
+		return nil, nil
+	}
+
+	ctype := w.Header().Get("Content-Type")
+	if ctype == "" {
+		ctype = mime.TypeByExtension(path.Ext(name))
+	}
+	if ctype == "" {
+		var buf [512]byte
+		n, _ := io.ReadFull(f, buf[:])
+		ctype = http.DetectContentType(buf[:n])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			gz.Close()
+			return nil, nil
+		}
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Encoding", "gzip")
+	return gz, info
+}
+
+// addVary adds field to the Vary header in h unless it is already there.
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		if v == field {
+			return
+		}
+	}
+	h.Add("Vary", field)
+}
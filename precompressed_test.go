@@ -0,0 +1,75 @@
+package httpfs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestPrecompressedSidecars(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("console.log('hi')"))
+	zw.Close()
+
+	mfs := newMemFS(t, map[string]string{
+		"/app.js":    "console.log('hi')",
+		"/app.js.gz": gz.String(),
+		"/plain.css": "body{}",
+	})
+	fs := httpfs.New(mfs, httpfs.WithPrecompressedSidecars(true))
+
+	get := func(name string, acceptGzip bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", name, nil)
+		if acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/app.js", true)
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !bytes.Equal(w.Body.Bytes(), gz.Bytes()) {
+		t.Error("sidecar bytes not served")
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(zr); string(data) != "console.log('hi')" {
+		t.Errorf("decompressed %q", data)
+	}
+
+	w = get("/app.js", false)
+	if ce := w.Header().Get("Content-Encoding"); ce != "" || w.Body.String() != "console.log('hi')" {
+		t.Errorf("without gzip: Content-Encoding %q, body %q", ce, w.Body.String())
+	}
+	if v := w.Header().Get("Vary"); v != "Accept-Encoding" {
+		t.Errorf("Vary = %q", v)
+	}
+
+	w = get("/plain.css", true)
+	if ce := w.Header().Get("Content-Encoding"); ce != "" || w.Body.String() != "body{}" {
+		t.Errorf("no sidecar: Content-Encoding %q, body %q", ce, w.Body.String())
+	}
+
+	if err := mfs.Remove("/app.js.gz"); err != nil {
+		t.Fatal(err)
+	}
+	w = get("/app.js", true)
+	if ce := w.Header().Get("Content-Encoding"); ce != "" || w.Body.String() != "console.log('hi')" {
+		t.Errorf("removed sidecar: Content-Encoding %q, body %q", ce, w.Body.String())
+	}
+
+}
@@ -0,0 +1,123 @@
+package httpfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// WithStripPrefix removes prefix from every name before it is passed to the
+// underlying filer, so that an Httpfs can be mounted under a URL path
+// without wrapping it in http.StripPrefix. Names are cleaned first, and names
+// outside prefix do not exist. A trailing slash on prefix is ignored.
+func WithStripPrefix(prefix string) Option {
+	return func(filer *Httpfs) {
+		filer.prefix = strings.TrimSuffix(path.Clean("/"+prefix), "/")
+	}
+}
+
+// WithSlashPaths enables or disables turning backslashes in names into
+// forward slashes, so that Windows-style names such as `\a\b` name the same
+// file as /a/b. It is enabled by default. Disable it for filers whose file
+// names may contain backslashes.
+func WithSlashPaths(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.noSlashPaths = !enabled
+	}
+}
+
+// slashPath returns name with its backslashes turned into forward slashes,
+// unless disabled with WithSlashPaths.
+func (filer *Httpfs) slashPath(name string) string {
+	if filer.noSlashPaths {
+		return name
+	}
+	return strings.ReplaceAll(name, `\`, "/")
+}
+
+// WithRewrite sets a function rewriting names before they are passed to the
+// underlying filer, such as to serve /latest/ as an alias for /versions/v3/.
+// It is called with the cleaned absolute name, after names escaping the root
+// are refused and before the prefix set with WithStripPrefix is removed, by
+// every method taking a name. Its result is cleaned in turn and must not
+// escape the root either.
+func WithRewrite(rewrite func(name string) string) Option {
+	return func(filer *Httpfs) {
+		filer.rewrite = rewrite
+	}
+}
+
+// WithAllowedPrefixes restricts the filesystem to the trees rooted at
+// prefixes: every operation on a name outside all of them fails with
+// os.ErrPermission. Unlike Sub, it leaves names unchanged, and may allow
+// several disjoint trees. Names are checked once cleaned and rewritten.
+// Options adding prefixes add to those already allowed.
+func WithAllowedPrefixes(prefixes ...string) Option {
+	return func(filer *Httpfs) {
+		for _, prefix := range prefixes {
+			filer.allowedPrefixes = append(filer.allowedPrefixes, path.Clean("/"+prefix))
+		}
+	}
+}
+
+// allowed reports whether the cleaned name is in one of the trees allowed
+// with WithAllowedPrefixes, if set.
+func (filer *Httpfs) allowed(clean string) bool {
+	if filer.allowedPrefixes == nil {
+		return true
+	}
+	for _, prefix := range filer.allowedPrefixes {
+		if clean == prefix || strings.HasPrefix(clean, dirPath(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the path in the underlying filer of the file name, or a
+// *os.PathError for op if there is none. Names are converted to forward
+// slashes and cleaned first, names escaping the root are invalid, the rest
+// are rewritten if WithRewrite is set, and names outside the allowed
+// prefixes are refused.
+func (filer *Httpfs) resolve(op, name string) (string, error) {
+	clean, err := cleanPath(op, filer.slashPath(name))
+	if err == nil && filer.rewrite != nil {
+		clean, err = cleanPath(op, filer.rewrite(clean))
+	}
+	if err == nil && !filer.allowed(clean) {
+		return "", &os.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	if err != nil || filer.prefix == "" {
+		return clean, err
+	}
+	if clean == filer.prefix {
+		return "/", nil
+	}
+	if !strings.HasPrefix(clean, filer.prefix+"/") {
+		return "", &os.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return clean[len(filer.prefix):], nil
+}
+
+// cleanPath returns name cleaned and made absolute, resolving its . and ..
+// elements against the root. It returns a *os.PathError for op wrapping
+// os.ErrInvalid if a .. element would climb above the root, rather than
+// clamping it there, as that is an attempt to reach files outside the
+// filesystem.
+func cleanPath(op, name string) (string, error) {
+	depth := 0
+	for _, elem := range strings.Split(name, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if depth == 0 {
+				return "", &os.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+			}
+			depth--
+		default:
+			depth++
+		}
+	}
+	return path.Clean("/" + name), nil
+}
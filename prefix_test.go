@@ -0,0 +1,212 @@
+package httpfs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestStripPrefix(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{
+		"/index.html":   "index",
+		"/css/main.css": "body{}",
+	})
+
+	for _, prefix := range []string{"/static", "/static/", "static"} {
+		fs := httpfs.New(mfs, httpfs.WithStripPrefix(prefix))
+
+		if got := readFile(t, fs, "/static/css/main.css"); got != "body{}" {
+			t.Errorf("%q: read %q", prefix, got)
+		}
+		for _, name := range []string{"/static", "/static/"} {
+			if info, err := fs.Stat(name); err != nil || !info.IsDir() {
+				t.Errorf("%q: Stat(%q) = %v, %v", prefix, name, info, err)
+			}
+		}
+		for _, name := range []string{"/css/main.css", "/staticfoo/css/main.css", "/static/../css/main.css"} {
+			if _, err := fs.Open(name); !os.IsNotExist(err) {
+				t.Errorf("%q: Open(%q): err = %v, want not exist", prefix, name, err)
+			}
+			if _, err := fs.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("%q: Stat(%q): err = %v, want not exist", prefix, name, err)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/static/", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "index" {
+			t.Errorf("%q: GET /static/ = %d %q", prefix, w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/css/main.css", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%q: GET outside the prefix = %d", prefix, w.Code)
+		}
+	}
+
+	fs := httpfs.New(mfs, httpfs.WithStripPrefix("/static"))
+	if err := fs.Mkdir("/static/js", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := mfs.Stat("/js"); err != nil || !info.IsDir() {
+		t.Errorf("Mkdir under the prefix: %v, %v", info, err)
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/etc/passwd": "inside",
+		"/bar":        "bar",
+		"/foo/a.txt":  "a",
+	}))
+
+	for _, name := range []string{
+		"/../etc/passwd",
+		"../etc/passwd",
+		"foo/../../bar",
+		"/foo/../../../bar",
+	} {
+		if _, err := fs.OpenFile(name, os.O_RDONLY, 0); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("OpenFile(%q) = %v, want os.ErrInvalid", name, err)
+		}
+		if _, err := fs.Stat(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("Stat(%q) = %v, want os.ErrInvalid", name, err)
+		}
+		if err := fs.MkdirAll(name+"/dir", 0755); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("MkdirAll(%q) = %v, want os.ErrInvalid", name, err)
+		}
+		if err := fs.RemoveAll(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("RemoveAll(%q) = %v, want os.ErrInvalid", name, err)
+		}
+	}
+	if _, err := fs.Stat("/bar"); err != nil {
+		t.Errorf("RemoveAll of an escaping path removed /bar: %v", err)
+	}
+
+	// Names climbing back down without leaving the root are fine.
+	for _, name := range []string{"foo/../bar", "/foo/./a.txt", "foo//a.txt"} {
+		if _, err := fs.Stat(name); err != nil {
+			t.Errorf("Stat(%q): %v", name, err)
+		}
+	}
+}
+
+func TestSlashPaths(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, nil))
+
+	if err := fs.MkdirAll(`\a\b\c`, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := fs.IsDir("/a/b/c"); !ok || err != nil {
+		t.Fatalf("/a/b/c not created: %v", err)
+	}
+	f, err := fs.OpenFile(`\a\b\c\file.txt`, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := fs.Stat("/a/b/c/file.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.Stat(`a\b/c\file.txt`); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.Stat(`\a\..\..\etc`); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("traversal with backslashes: err = %v", err)
+	}
+
+	// disabled, backslashes are part of names
+	literal := httpfs.New(newMemFS(t, nil), httpfs.WithSlashPaths(false))
+	if err := literal.MkdirAll(`\x\y`, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := literal.Stat("/x"); !os.IsNotExist(err) {
+		t.Errorf("Stat(/x) err = %v, want not exist", err)
+	}
+	if _, err := literal.Stat(`/\x\y`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/versions/v3/app.js": "v3",
+		"/versions/v2/app.js": "v2",
+	}), httpfs.WithRewrite(func(name string) string {
+		if strings.HasPrefix(name, "/latest/") {
+			return "/versions/v3/" + strings.TrimPrefix(name, "/latest/")
+		}
+		if name == "/escape" {
+			return "../../etc/passwd"
+		}
+		return name
+	}))
+
+	if got := readFile(t, fs, "/latest/app.js"); got != "v3" {
+		t.Errorf("/latest/app.js = %q, want v3", got)
+	}
+	if got := readFile(t, fs, "/versions/v2/app.js"); got != "v2" {
+		t.Errorf("/versions/v2/app.js = %q, want v2", got)
+	}
+	if _, err := fs.Stat("/latest/../latest/app.js"); err != nil {
+		t.Error(err)
+	}
+	// the rewrite sees names already checked for traversal
+	if _, err := fs.Stat("/latest/../../app.js"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("traversal err = %v", err)
+	}
+	if _, err := fs.Stat("/escape"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("rewrite escaping the root: err = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/app.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "v3" {
+		t.Errorf("GET /latest/app.js = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestAllowedPrefixes(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/tenants/a/doc.txt":  "a",
+		"/tenants/b/doc.txt":  "b",
+		"/tenants/ab/doc.txt": "ab",
+		"/shared/logo.png":    "logo",
+		"/secret.txt":         "secret",
+	}), httpfs.WithAllowedPrefixes("/tenants/a", "shared/"))
+
+	for _, name := range []string{"/tenants/a/doc.txt", "/tenants/a", "/shared/logo.png", "shared/../shared/logo.png"} {
+		if _, err := fs.Stat(name); err != nil {
+			t.Errorf("Stat(%s): %v", name, err)
+		}
+	}
+	for _, name := range []string{"/tenants/b/doc.txt", "/tenants/ab/doc.txt", "/tenants", "/", "/secret.txt", "/shared/../secret.txt"} {
+		if _, err := fs.Stat(name); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("Stat(%s) err = %v, want %v", name, err, os.ErrPermission)
+		}
+	}
+	if _, err := fs.Open("/secret.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Open(/secret.txt) err = %v", err)
+	}
+	if err := fs.Remove("/tenants/b/doc.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Remove err = %v", err)
+	}
+
+	if err := fs.MkdirAll("/tenants/a/uploads/2024", 0755); err != nil {
+		t.Errorf("MkdirAll in an allowed tree: %v", err)
+	}
+	if err := fs.MkdirAll("/tenants/c/uploads", 0755); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("MkdirAll outside the allowed trees: err = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	fs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/secret.txt", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /secret.txt status = %d", rec.Code)
+	}
+}
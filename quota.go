@@ -0,0 +1,270 @@
+package httpfs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/absfs/absfs"
+	"github.com/pkg/errors"
+)
+
+// ErrQuotaExceeded is returned when a write would take the bytes stored
+// beyond the quota set with WithQuota. The handler answers it with 507
+// Insufficient Storage.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// WithQuota limits the total size of the files written through the Httpfs to
+// maxTotalBytes. Writes growing a file opened with OpenFile, including those
+// of WriteFileAtomic and uploads, and truncations extending one, fail with
+// ErrQuotaExceeded if they would exceed the quota. Truncating, removing or
+// renaming over a file frees the bytes charged for it. Usage starts at zero
+// when the Httpfs is created: files already in the underlying filer are not
+// counted, and removing them frees no quota, although the bytes they are
+// later grown by are charged and freed.
+func WithQuota(maxTotalBytes int64) Option {
+	return func(filer *Httpfs) {
+		filer.quota = &quota{quotaUsage: &quotaUsage{max: maxTotalBytes, charged: make(map[string]int64)}}
+	}
+}
+
+// quota charges the bytes written to the files of an underlying filer
+// against a quotaUsage. A quota over a subtree of a filer, such as the
+// directory ReplaceDir fills in, shares the usage of the quota of the filer
+// and charges its files by their paths in that filer.
+type quota struct {
+	*quotaUsage
+	// base is the directory the underlying filer is rooted at in the filer
+	// whose paths files are charged by.
+	base string
+}
+
+// within returns the quota for a filer rooted at the directory dir of the
+// underlying filer of q.
+func (q *quota) within(dir string) *quota {
+	return &quota{quotaUsage: q.quotaUsage, base: path.Join(q.base, dir)}
+}
+
+// key returns the path the file at p in the underlying filer is charged by.
+func (q *quota) key(p string) string {
+	if q.base == "" {
+		return p
+	}
+	return path.Join(q.base, p)
+}
+
+// quotaUsage tracks the bytes written against a maximum, and the files they
+// were written to.
+type quotaUsage struct {
+	max  int64
+	used atomic.Int64
+
+	mu sync.Mutex
+	// charged holds the bytes charged to each file by its path in the
+	// underlying filer.
+	charged map[string]int64
+}
+
+// add adds n bytes, which may be negative to free them, to the bytes used.
+// It reports false, changing nothing, if that would exceed the quota. Usage
+// never drops below zero.
+func (q *quotaUsage) add(n int64) bool {
+	for {
+		used := q.used.Load()
+		next := used + n
+		if n > 0 && next > q.max {
+			return false
+		}
+		if next < 0 {
+			next = 0
+		}
+		if q.used.CompareAndSwap(used, next) {
+			return true
+		}
+	}
+}
+
+// charge charges n bytes to the file at p. It reports false, changing
+// nothing, if that would exceed the quota.
+func (q *quota) charge(p string, n int64) bool {
+	if !q.add(n) {
+		return false
+	}
+	p = q.key(p)
+	q.mu.Lock()
+	q.charged[p] += n
+	q.mu.Unlock()
+	return true
+}
+
+// refund frees up to n of the bytes charged to the file at p, for example
+// when it shrinks by n bytes. Bytes the file held before they could be
+// charged are not freed.
+func (q *quota) refund(p string, n int64) {
+	p = q.key(p)
+	q.mu.Lock()
+	charged := q.charged[p]
+	if n > charged {
+		n = charged
+	}
+	if n == charged {
+		delete(q.charged, p)
+	} else {
+		q.charged[p] = charged - n
+	}
+	q.mu.Unlock()
+	if n > 0 {
+		q.add(-n)
+	}
+}
+
+// full reports whether no byte could be written to the file at p once the
+// bytes charged to it are freed, as when it is replaced.
+func (q *quota) full(p string) bool {
+	q.mu.Lock()
+	charged := q.charged[q.key(p)]
+	q.mu.Unlock()
+	return q.used.Load()-charged >= q.max
+}
+
+// move moves the bytes charged to the file or directory tree at oldp to
+// newp, after it was renamed.
+func (q *quota) move(oldp, newp string) {
+	oldp, newp = q.key(oldp), q.key(newp)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for p, n := range q.charged {
+		switch {
+		case p == oldp:
+			delete(q.charged, p)
+			q.charged[newp] += n
+		case strings.HasPrefix(p, dirPath(oldp)):
+			delete(q.charged, p)
+			q.charged[path.Join(newp, p[len(oldp):])] += n
+		}
+	}
+}
+
+// free frees the bytes charged to the file at p in the underlying filer, if
+// it is a regular file, once remove succeeds.
+func (filer *Httpfs) free(p string, remove func() error) error {
+	if filer.quota == nil {
+		return remove()
+	}
+	info, statErr := filer.fs.Stat(p)
+	err := remove()
+	if err == nil && statErr == nil && info.Mode().IsRegular() {
+		filer.quota.refund(p, info.Size())
+	}
+	return err
+}
+
+// quotaFile is a file open for writing whose growth is charged to a quota.
+type quotaFile struct {
+	absfs.File
+	q      *quota
+	name   string
+	p      string
+	append bool
+
+	// pos is the offset of the next read or write and size the size of the
+	// file, as far as known.
+	pos, size int64
+}
+
+// newQuotaFile wraps f, the file name at p in the underlying filer opened
+// with flag, whose size was size.
+func newQuotaFile(f absfs.File, q *quota, name, p string, flag int, size int64) *quotaFile {
+	return &quotaFile{File: f, q: q, name: name, p: p, append: flag&os.O_APPEND != 0, size: size}
+}
+
+// grow charges the growth of the file from writing n bytes at off, returning
+// the bytes charged.
+func (f *quotaFile) grow(off int64, n int) (int64, error) {
+	growth := off + int64(n) - f.size
+	if growth <= 0 {
+		return 0, nil
+	}
+	if !f.q.charge(f.p, growth) {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrQuotaExceeded}
+	}
+	return growth, nil
+}
+
+// wrote records that n bytes were written at off after charging charged
+// bytes, refunding those not used.
+func (f *quotaFile) wrote(off int64, n int, charged int64) {
+	end := off + int64(n)
+	if used := end - f.size; used < charged {
+		if used < 0 {
+			used = 0
+		}
+		f.q.refund(f.p, charged-used)
+	}
+	if end > f.size {
+		f.size = end
+	}
+}
+
+func (f *quotaFile) Write(p []byte) (int, error) {
+	if f.append {
+		f.pos = f.size
+	}
+	charged, err := f.grow(f.pos, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.File.Write(p)
+	f.wrote(f.pos, n, charged)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *quotaFile) WriteAt(p []byte, off int64) (int, error) {
+	charged, err := f.grow(off, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.File.WriteAt(p, off)
+	f.wrote(off, n, charged)
+	return n, err
+}
+
+func (f *quotaFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *quotaFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *quotaFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.File.Seek(offset, whence)
+	if err == nil {
+		f.pos = pos
+	}
+	return pos, err
+}
+
+func (f *quotaFile) Truncate(size int64) error {
+	delta := size - f.size
+	if delta > 0 && !f.q.charge(f.p, delta) {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: ErrQuotaExceeded}
+	}
+	err := f.File.Truncate(size)
+	if err != nil {
+		if delta > 0 {
+			f.q.refund(f.p, delta)
+		}
+		return err
+	}
+	if delta < 0 {
+		f.q.refund(f.p, -delta)
+	}
+	f.size = size
+	return nil
+}
@@ -0,0 +1,124 @@
+package httpfs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestQuota(t *testing.T) {
+	fs := httpfs.New(&renameFS{Filer: newMemFS(t, nil), renamed: map[string]string{}}, httpfs.WithQuota(10))
+
+	write := func(name, data string, flag int) error {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY|flag, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte(data))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+
+	if err := write("/a.txt", "aaaaaa", os.O_TRUNC); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/b.txt", "bbbb", os.O_TRUNC); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/b.txt", "b", os.O_APPEND); !errors.Is(err, httpfs.ErrQuotaExceeded) {
+		t.Fatalf("write beyond the quota = %v, want ErrQuotaExceeded", err)
+	}
+	// Overwriting in place does not grow the file.
+	if err := write("/b.txt", "BB", 0); err != nil {
+		t.Errorf("overwrite within a file: %v", err)
+	}
+	if got := readFile(t, fs, "/b.txt"); got != "BBbb" {
+		t.Errorf("b.txt = %q", got)
+	}
+
+	if err := fs.Truncate("/a.txt", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/c.txt", "cccc", os.O_TRUNC); err != nil {
+		t.Errorf("write after truncating: %v", err)
+	}
+	if err := fs.Truncate("/c.txt", 5); !errors.Is(err, httpfs.ErrQuotaExceeded) {
+		t.Errorf("truncate beyond the quota = %v, want ErrQuotaExceeded", err)
+	}
+
+	if err := fs.Remove("/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFileAtomic("/d.txt", []byte("dddd"), 0644); err != nil {
+		t.Errorf("write after removing: %v", err)
+	}
+	// An atomic write needs room for both copies until the rename, which
+	// frees the old one.
+	if err := fs.WriteFileAtomic("/d.txt", []byte("DDDD"), 0644); !errors.Is(err, httpfs.ErrQuotaExceeded) {
+		t.Errorf("replacing a file without room for both copies = %v, want ErrQuotaExceeded", err)
+	}
+	if err := fs.Remove("/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := fs.WriteFileAtomic("/d.txt", []byte("DDDD"), 0644); err != nil {
+			t.Errorf("replacing a file: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("PUT", "/e.txt", strings.NewReader("eeeeeeeeee")))
+	if w.Code != http.StatusInsufficientStorage {
+		t.Errorf("upload beyond the quota: status %d", w.Code)
+	}
+}
+
+func TestQuotaExistingFiles(t *testing.T) {
+	fs := httpfs.New(&renameFS{Filer: newMemFS(t, map[string]string{
+		"/old.bin":   strings.Repeat("x", 1000),
+		"/other.bin": strings.Repeat("y", 1000),
+	}), renamed: map[string]string{}}, httpfs.WithQuota(100))
+
+	write := func(name string, n, flag int) error {
+		f, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY|flag, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte(strings.Repeat("z", n)))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+
+	if err := write("/a.bin", 100, os.O_TRUNC); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/old.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/other.bin", 0, os.O_TRUNC); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/b.bin", 100, os.O_TRUNC); !errors.Is(err, httpfs.ErrQuotaExceeded) {
+		t.Errorf("write after freeing files not charged = %v, want ErrQuotaExceeded", err)
+	}
+
+	// Bytes charged follow their file when it is renamed.
+	if err := fs.Rename("/a.bin", "/c.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/c.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := write("/b.bin", 100, os.O_TRUNC); err != nil {
+		t.Errorf("write after removing a charged file: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package httpfs
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithReadRateLimit paces reads from each file opened with Open or served
+// over HTTP to bytesPerSec, so that a few large downloads cannot saturate the
+// link. Each file gets its own token bucket, allowing bursts of up to a
+// second's worth of bytes. Reads waiting for tokens give up when the request
+// context is done. A limit of zero or less disables pacing.
+func WithReadRateLimit(bytesPerSec int64) Option {
+	return func(filer *Httpfs) {
+		filer.readRate = bytesPerSec
+	}
+}
+
+// tokenBucket holds up to rate tokens, refilled at rate tokens per second.
+type tokenBucket struct {
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: float64(rate), last: time.Now()}
+}
+
+// take waits until n tokens, at most rate, are available and takes them.
+// It returns ctx.Err() if ctx is done first.
+func (b *tokenBucket) take(ctx context.Context, n int) error {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+	if max := float64(b.rate); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return nil
+	}
+	wait := time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Nothing was read: give the tokens back.
+		b.tokens += float64(n)
+		return ctx.Err()
+	}
+}
+
+// give returns n unused tokens.
+func (b *tokenBucket) give(n int) {
+	b.tokens += float64(n)
+}
+
+// throttledFile paces the reads from a file with a token bucket.
+type throttledFile struct {
+	http.File
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (f *throttledFile) Read(p []byte) (int, error) {
+	if int64(len(p)) > f.bucket.rate {
+		p = p[:f.bucket.rate]
+	}
+	if err := f.bucket.take(f.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	n, err := f.File.Read(p)
+	f.bucket.give(len(p) - n)
+	return n, err
+}
@@ -0,0 +1,47 @@
+package httpfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestReadRateLimit(t *testing.T) {
+	const rate = 100 * 1024
+	data := strings.Repeat("x", rate*3/2)
+	fs := httpfs.New(newMemFS(t, map[string]string{"/big.bin": data}), httpfs.WithReadRateLimit(rate))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	fs.ServeHTTP(w, httptest.NewRequest("GET", "/big.bin", nil))
+	elapsed := time.Since(start)
+	if w.Body.Len() != len(data) {
+		t.Fatalf("served %d bytes, want %d", w.Body.Len(), len(data))
+	}
+	// The first second's worth is a burst, the rest is paced.
+	if min := 500 * time.Millisecond; elapsed < min {
+		t.Errorf("served in %v, want at least %v", elapsed, min)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	f, err := fs.OpenContext(ctx, "/big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	start = time.Now()
+	_, err = io.Copy(io.Discard, f)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("read with an expiring context = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("read kept waiting %v after the context expired", elapsed)
+	}
+}
@@ -0,0 +1,216 @@
+package httpfs_test
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+var errDirChanged = errors.New("directory changed during read")
+
+// partialReaddirFS returns every directory's entries together with
+// errDirChanged from Readdir.
+type partialReaddirFS struct {
+	absfs.Filer
+}
+
+func (fs *partialReaddirFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &partialReaddirFile{f}, nil
+}
+
+type partialReaddirFile struct {
+	absfs.File
+}
+
+func (f *partialReaddirFile) Readdir(n int) ([]os.FileInfo, error) {
+	infos, _ := f.File.Readdir(n)
+	return infos, errDirChanged
+}
+
+func TestReaddirPartialResults(t *testing.T) {
+	files := map[string]string{
+		"/dir/a.txt": "a",
+		"/dir/b.txt": "b",
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		fs := httpfs.New(&partialReaddirFS{newMemFS(t, files)})
+
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/dir/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("listing status = %d, want %d", w.Code, http.StatusInternalServerError)
+		}
+
+		err := fs.RemoveAll("/dir")
+		if err != errDirChanged {
+			t.Fatalf("RemoveAll err = %v, want %v", err, errDirChanged)
+		}
+		if _, err := fs.Stat("/dir/a.txt"); !os.IsNotExist(err) {
+			t.Errorf("returned entry not removed: %v", err)
+		}
+		if _, err := fs.Stat("/dir"); err != nil {
+			t.Errorf("directory removed despite Readdir error: %v", err)
+		}
+	})
+
+	t.Run("tolerant", func(t *testing.T) {
+		fs := httpfs.New(&partialReaddirFS{newMemFS(t, files)}, httpfs.WithTolerateReaddirErrors(true))
+
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", "/dir/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("listing status = %d", w.Code)
+		}
+		for name := range files {
+			if !strings.Contains(w.Body.String(), name[len("/dir/"):]) {
+				t.Errorf("listing missing %s:\n%s", name, w.Body.String())
+			}
+		}
+
+		err := fs.RemoveAll("/dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+			t.Errorf("directory not removed: %v", err)
+		}
+	})
+}
+
+// emptyReaddirFS lists regular files as empty directories, as some filers do.
+type emptyReaddirFS struct {
+	absfs.Filer
+}
+
+func (fs *emptyReaddirFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &emptyReaddirFile{f}, nil
+}
+
+type emptyReaddirFile struct {
+	absfs.File
+}
+
+func (f *emptyReaddirFile) Readdir(n int) ([]os.FileInfo, error) {
+	return nil, nil
+}
+
+func TestReadDir(t *testing.T) {
+	files := map[string]string{
+		"/dir/b.txt":     "b",
+		"/dir/a.txt":     "a",
+		"/dir/sub/c.txt": "c",
+	}
+	mfs := newMemFS(t, files)
+
+	entries, err := httpfs.New(mfs).ReadDir("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "a.txt,b.txt,sub" || !entries[2].IsDir() {
+		t.Errorf("ReadDir = %v", names)
+	}
+
+	backends := map[string]absfs.Filer{
+		"memfs":        mfs,
+		"emptyReaddir": &emptyReaddirFS{mfs},
+	}
+	for name, backend := range backends {
+		_, err := httpfs.New(backend).ReadDir("/dir/a.txt")
+		var perr *os.PathError
+		if !errors.As(err, &perr) || !errors.Is(err, syscall.ENOTDIR) {
+			t.Errorf("%s: ReadDir on a file: err = %v, want ENOTDIR *os.PathError", name, err)
+		}
+	}
+}
+
+// countingReaddirFS counts the Readdir calls on the files it opens.
+type countingReaddirFS struct {
+	absfs.Filer
+	calls int
+}
+
+func (fs *countingReaddirFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReaddirFile{File: f, fs: fs}, nil
+}
+
+type countingReaddirFile struct {
+	absfs.File
+	fs *countingReaddirFS
+}
+
+func (f *countingReaddirFile) Readdir(n int) ([]os.FileInfo, error) {
+	f.fs.calls++
+	return f.File.Readdir(n)
+}
+
+func TestReadDirIter(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 2500; i++ {
+		files[fmt.Sprintf("/big/%05d.txt", i)] = ""
+	}
+	cfs := &countingReaddirFS{Filer: newMemFS(t, files)}
+	fs := httpfs.New(cfs)
+
+	seen := make(map[string]bool)
+	err := fs.ReadDirIter("/big", func(e iofs.DirEntry) error {
+		seen[e.Name()] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2500 {
+		t.Errorf("saw %d entries, want 2500", len(seen))
+	}
+
+	cfs.calls = 0
+	n := 0
+	err = fs.ReadDirIter("/big", func(e iofs.DirEntry) error {
+		n++
+		if n == 10 {
+			return iofs.SkipAll
+		}
+		return nil
+	})
+	if err != nil || n != 10 {
+		t.Errorf("ReadDirIter stopping early = %v after %d entries", err, n)
+	}
+	if cfs.calls != 1 {
+		t.Errorf("%d Readdir calls, want 1", cfs.calls)
+	}
+
+	errStop := errors.New("stop")
+	err = fs.ReadDirIter("/big", func(e iofs.DirEntry) error { return errStop })
+	if err != errStop {
+		t.Errorf("ReadDirIter = %v, want the error from fn", err)
+	}
+	if err := fs.ReadDirIter("/big/00001.txt", func(iofs.DirEntry) error { return nil }); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("ReadDirIter of a file = %v, want ENOTDIR", err)
+	}
+}
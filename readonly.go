@@ -0,0 +1,24 @@
+package httpfs
+
+import (
+	"io/fs"
+	"os"
+)
+
+// WithReadOnly refuses every operation that would modify the underlying
+// filer with fs.ErrPermission, including OpenFile with any write flag.
+// Reading, including Open, keeps working.
+func WithReadOnly(readOnly bool) Option {
+	return func(filer *Httpfs) {
+		filer.readOnly = readOnly
+	}
+}
+
+// checkReadOnly returns a *os.PathError for the operation op on name if the
+// filesystem is read-only.
+func (filer *Httpfs) checkReadOnly(op, name string) error {
+	if filer.readOnly {
+		return &os.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return nil
+}
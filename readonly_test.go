@@ -0,0 +1,63 @@
+package httpfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestReadOnly(t *testing.T) {
+	filer := httpfs.New(newMemFS(t, map[string]string{"/dir/file.txt": "data"}), httpfs.WithReadOnly(true))
+
+	now := time.Now()
+	mutations := map[string]func() error{
+		"OpenFile O_WRONLY": func() error { _, err := filer.OpenFile("/dir/file.txt", os.O_WRONLY, 0); return err },
+		"OpenFile O_RDWR":   func() error { _, err := filer.OpenFile("/dir/file.txt", os.O_RDWR, 0); return err },
+		"OpenFile O_CREATE": func() error { _, err := filer.OpenFile("/new.txt", os.O_CREATE, 0644); return err },
+		"OpenFile O_TRUNC":  func() error { _, err := filer.OpenFile("/dir/file.txt", os.O_TRUNC, 0); return err },
+		"Mkdir":             func() error { return filer.Mkdir("/new", 0755) },
+		"MkdirAll":          func() error { return filer.MkdirAll("/a/b", 0755) },
+		"Remove":            func() error { return filer.Remove("/dir/file.txt") },
+		"RemoveAll":         func() error { return filer.RemoveAll("/dir") },
+		"Chmod":             func() error { return filer.Chmod("/dir/file.txt", 0600) },
+		"Chtimes":           func() error { return filer.Chtimes("/dir/file.txt", now, now) },
+		"Chown":             func() error { return filer.Chown("/dir/file.txt", 1, 1) },
+		"WriteFileAtomic":   func() error { return filer.WriteFileAtomic("/dir/file.txt", nil, 0644) },
+		"Rename":            func() error { return filer.Rename("/dir/file.txt", "/moved.txt") },
+		"Truncate":          func() error { return filer.Truncate("/dir/file.txt", 0) },
+	}
+	for name, mutate := range mutations {
+		if err := mutate(); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("%s: err = %v, want %v", name, err, fs.ErrPermission)
+		}
+	}
+
+	f, err := filer.Open("/dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "data" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+
+	for _, method := range []string{"PUT", "DELETE"} {
+		w := httptest.NewRecorder()
+		filer.ServeHTTP(w, httptest.NewRequest(method, "/dir/file.txt", strings.NewReader("x")))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want %d", method, w.Code, http.StatusForbidden)
+		}
+	}
+	if got := readFile(t, filer, "/dir/file.txt"); got != "data" {
+		t.Errorf("file changed to %q", got)
+	}
+}
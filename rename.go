@@ -0,0 +1,174 @@
+package httpfs
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// Rename renames oldpath to newpath. It delegates to the underlying filer if
+// it can rename files, and otherwise copies oldpath to newpath, recursively
+// for directories, and then removes oldpath. The fallback is not atomic: if
+// it fails part way both paths may be left holding some of the files.
+// Hidden files cannot be renamed, or renamed to. newpath, and the paths the
+// files in a directory are moved to, are checked as writes to them would be,
+// so that a rename cannot place a file where it could not be written.
+func (filer *Httpfs) Rename(oldpath, newpath string) error {
+	if filer.readOnly {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrPermission}
+	}
+	if filer.hidden(oldpath) || filer.hidden(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if err := filer.checkRename(oldpath, newpath); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: underlying(err)}
+	}
+	if r, ok := filer.fs.(renamer); ok {
+		if err := filer.acquire(); err != nil {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+		}
+		defer filer.release()
+		start := filer.startOp()
+		err := filer.rename(r, oldpath, newpath)
+		filer.endOp(opRename, oldpath, start, err)
+		return err
+	}
+
+	oldpath, newpath = path.Clean("/"+oldpath), path.Clean("/"+newpath)
+	if oldpath == newpath {
+		return nil
+	}
+	info, err := filer.Stat(oldpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: underlying(err)}
+	}
+	if info.IsDir() && strings.HasPrefix(newpath, dirPath(oldpath)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EINVAL}
+	}
+	err = filer.copyTree(oldpath, newpath, info)
+	if err != nil {
+		return err
+	}
+	return filer.removeAll(oldpath)
+}
+
+// renameOver renames oldpath to newpath as Rename does, replacing newpath,
+// which exists. On filers that can rename files newpath is renamed aside
+// first, and removed only once the rename succeeds or put back if it fails.
+// Otherwise newpath is removed once the checks Rename makes pass, so that
+// only a failure copying the files can lose it.
+func (filer *Httpfs) renameOver(oldpath, newpath string) error {
+	r, ok := filer.fs.(renamer)
+	if !ok {
+		if err := filer.checkRenameOver(oldpath, newpath); err != nil {
+			return err
+		}
+		if err := filer.RemoveAll(newpath); err != nil {
+			return err
+		}
+		return filer.Rename(oldpath, newpath)
+	}
+
+	if err := filer.checkReadOnly("rename", newpath); err != nil {
+		return err
+	}
+	newpath = path.Clean("/" + newpath)
+	aside := tempPath(path.Dir(newpath), newpath)
+	if err := filer.rename(r, newpath, aside); err != nil {
+		return err
+	}
+	if err := filer.Rename(oldpath, newpath); err != nil {
+		filer.rename(r, aside, newpath)
+		return err
+	}
+	return filer.removeAll(aside)
+}
+
+// checkRenameOver returns the error Rename would fail with before copying
+// any file if newpath did not exist.
+func (filer *Httpfs) checkRenameOver(oldpath, newpath string) error {
+	if filer.readOnly {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrPermission}
+	}
+	if filer.hidden(oldpath) || filer.hidden(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	// Directories in the way are removed along with newpath.
+	if err := filer.checkRename(oldpath, newpath); err != nil && !errors.Is(err, syscall.EISDIR) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: underlying(err)}
+	}
+	return nil
+}
+
+// checkRename returns an error if oldpath may not be renamed to newpath
+// because newpath, or for a directory the path any of its files would be
+// moved to, may not be written.
+func (filer *Httpfs) checkRename(oldpath, newpath string) error {
+	info, err := filer.stat(oldpath)
+	if err != nil {
+		return err
+	}
+	return filer.checkMove(oldpath, newpath, info)
+}
+
+// checkMove checks the move of the file or directory oldpath, whose info is
+// given, to newpath as checkRename does.
+func (filer *Httpfs) checkMove(oldpath, newpath string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return filer.checkWrite(newpath)
+	}
+	if _, err := filer.checkDenied(newpath); err != nil {
+		return err
+	}
+	infos, err := filer.readDirInfos(oldpath)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		err = filer.checkMove(path.Join(oldpath, info.Name()), path.Join(newpath, info.Name()), info)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// underlying returns the error wrapped by a *os.PathError, or err itself.
+func underlying(err error) error {
+	if perr, ok := err.(*os.PathError); ok {
+		return perr.Err
+	}
+	return err
+}
+
+// rename renames oldpath to newpath with r, resolving both paths. Errors
+// from r are returned as a *os.LinkError unless they already are one or a
+// *os.PathError.
+func (filer *Httpfs) rename(r renamer, oldpath, newpath string) error {
+	oldp, err := filer.resolve("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	newp, err := filer.resolve("rename", newpath)
+	if err != nil {
+		return err
+	}
+	rename := func() error { return r.Rename(oldp, newp) }
+	unlock := filer.lock2(oldp, newp)
+	if oldp == newp {
+		err = rename()
+	} else {
+		err = filer.free(newp, rename)
+		if err == nil && filer.quota != nil {
+			filer.quota.move(oldp, newp)
+		}
+	}
+	unlock()
+	switch err.(type) {
+	case nil, *os.LinkError, *os.PathError:
+		return err
+	}
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+}
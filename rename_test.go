@@ -0,0 +1,126 @@
+package httpfs_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+func TestRename(t *testing.T) {
+	files := map[string]string{
+		"/file.txt":       "file",
+		"/dir/a.txt":      "a",
+		"/dir/sub/b.txt":  "b",
+		"/dir/sub/deep/c": "c",
+		"/other/keep.txt": "keep",
+	}
+	for _, tc := range []struct {
+		name string
+		new  func(t *testing.T) *httpfs.Httpfs
+	}{
+		{"delegated", func(t *testing.T) *httpfs.Httpfs {
+			return httpfs.New(&renameFS{Filer: newMemFS(t, files), renamed: map[string]string{}})
+		}},
+		{"fallback", func(t *testing.T) *httpfs.Httpfs {
+			return httpfs.New(newMemFS(t, files))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := tc.new(t)
+
+			if err := fs.Rename("/file.txt", "/other/moved.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, fs, "/other/moved.txt"); got != "file" {
+				t.Errorf("moved file = %q, want %q", got, "file")
+			}
+			if _, err := fs.Stat("/file.txt"); !os.IsNotExist(err) {
+				t.Errorf("old file still exists: %v", err)
+			}
+
+			if err := fs.Rename("/dir", "/renamed"); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range map[string]string{
+				"/renamed/a.txt":      "a",
+				"/renamed/sub/b.txt":  "b",
+				"/renamed/sub/deep/c": "c",
+				"/other/keep.txt":     "keep",
+			} {
+				if got := readFile(t, fs, name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+				t.Errorf("old directory still exists: %v", err)
+			}
+
+			if err := fs.Rename("/missing", "/x"); !os.IsNotExist(err) {
+				t.Errorf("renaming a missing file: err = %v, want not exist", err)
+			}
+		})
+	}
+}
+
+func TestRenameIntoItself(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/dir/a.txt": "a"}))
+	if err := fs.Rename("/dir", "/dir/sub"); err == nil {
+		t.Fatal("renaming a directory into itself succeeded")
+	}
+	if got := readFile(t, fs, "/dir/a.txt"); got != "a" {
+		t.Errorf("/dir/a.txt = %q, want %q", got, "a")
+	}
+}
+
+func TestRenameChecks(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		wrap func(absfs.Filer) absfs.Filer
+	}{
+		{"delegated", func(fs absfs.Filer) absfs.Filer { return &renameFS{Filer: fs, renamed: map[string]string{}} }},
+		{"fallback", func(fs absfs.Filer) absfs.Filer { return fs }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var o countingObserver
+			fs := httpfs.New(tc.wrap(newMemFS(t, map[string]string{
+				"/a.txt":       "a",
+				"/.secret.txt": "secret",
+				"/dir/b.txt":   "b",
+			})),
+				httpfs.WithAllowedExtensions(".txt"),
+				httpfs.WithDenyGlobs("*.php", "/private/*"),
+				httpfs.WithHideDotfiles(true),
+				httpfs.WithObserver(&o))
+
+			for _, newpath := range []string{"/a.php", "/a.html", "/private/a.txt"} {
+				if err := fs.Rename("/a.txt", newpath); !os.IsPermission(err) {
+					t.Errorf("Rename(/a.txt, %s): err = %v, want permission denied", newpath, err)
+				}
+			}
+			if err := fs.Rename("/dir", "/private"); !os.IsPermission(err) {
+				t.Errorf("Rename(/dir, /private): err = %v, want permission denied", err)
+			}
+			if err := fs.Rename("/a.txt", "/.a.txt"); !os.IsNotExist(err) {
+				t.Errorf("rename to a hidden file: err = %v, want not exist", err)
+			}
+			if err := fs.Rename("/.secret.txt", "/b.txt"); !os.IsNotExist(err) {
+				t.Errorf("rename of a hidden file: err = %v, want not exist", err)
+			}
+			if got := readFile(t, fs, "/a.txt"); got != "a" {
+				t.Errorf("/a.txt after refused renames = %q", got)
+			}
+
+			if err := fs.Rename("/a.txt", "/c.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fs.Rename("/dir", "/moved"); err != nil {
+				t.Fatal(err)
+			}
+			if tc.name == "delegated" && o.calls["rename"] != 2 {
+				t.Errorf("observed renames = %d, want 2", o.calls["rename"])
+			}
+		})
+	}
+}
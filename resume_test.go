@@ -0,0 +1,57 @@
+package httpfs_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestResumableDownload(t *testing.T) {
+	data := strings.Repeat("resumable download. ", 200)
+	for name, fs := range map[string]*httpfs.Httpfs{
+		"plain":      httpfs.New(newMemFS(t, map[string]string{"/big.txt": data})),
+		"compressed": httpfs.New(newMemFS(t, map[string]string{"/big.txt": data}), httpfs.WithResponseCompression(0)),
+	} {
+		// HEAD reports the headers of the matching GET: the full length
+		// is given to clients not accepting compressed responses, while
+		// ranges are always served uncompressed.
+		req := httptest.NewRequest("HEAD", "/big.txt", nil)
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		if ar := w.Header().Get("Accept-Ranges"); ar != "bytes" {
+			t.Errorf("%s: Accept-Ranges = %q", name, ar)
+		}
+		if w.Header().Get("Last-Modified") == "" {
+			t.Errorf("%s: missing Last-Modified", name)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%s: HEAD returned a body", name)
+		}
+		size, err := strconv.Atoi(w.Header().Get("Content-Length"))
+		if err != nil || size != len(data) {
+			t.Fatalf("%s: Content-Length = %q, want %d", name, w.Header().Get("Content-Length"), len(data))
+		}
+
+		mid := size / 2
+		var got strings.Builder
+		for _, rng := range []string{fmt.Sprintf("bytes=0-%d", mid-1), fmt.Sprintf("bytes=%d-", mid)} {
+			req = httptest.NewRequest("GET", "/big.txt", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			req.Header.Set("Range", rng)
+			w = httptest.NewRecorder()
+			fs.ServeHTTP(w, req)
+			if w.Code != http.StatusPartialContent {
+				t.Fatalf("%s: %s: status = %d", name, rng, w.Code)
+			}
+			got.Write(w.Body.Bytes())
+		}
+		if got.String() != data {
+			t.Errorf("%s: assembled content differs", name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package httpfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// WithRetry retries opening files, and reading from open files, on errors
+// from the underlying filer that isRetryable reports as transient, such as
+// those of a network filer, making up to attempts attempts in all. The
+// first retry waits backoff, and each further retry twice as long as the one
+// before. Retries of files opened with Open, OpenContext or over HTTP give up
+// once the context is done. If isRetryable is nil, all errors are retried
+// but those reporting that a file does not exist or already exists,
+// permission errors, invalid arguments and context errors. io.EOF is never
+// retried, nor are opens with os.O_EXCL, which may have created the file.
+func WithRetry(attempts int, backoff time.Duration, isRetryable func(error) bool) Option {
+	return func(filer *Httpfs) {
+		if attempts <= 1 {
+			filer.retries = nil
+			return
+		}
+		if isRetryable == nil {
+			isRetryable = transient
+		}
+		filer.retries = &retryPolicy{attempts: attempts, backoff: backoff, retryable: isRetryable}
+	}
+}
+
+// retryPolicy is the policy set with WithRetry.
+type retryPolicy struct {
+	attempts  int
+	backoff   time.Duration
+	retryable func(error) bool
+}
+
+// transient reports whether err may go away if the operation is retried.
+func transient(err error) bool {
+	return !os.IsNotExist(err) && !os.IsExist(err) && !os.IsPermission(err) &&
+		!errors.Is(err, os.ErrInvalid) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retry calls fn, calling it again as long as it fails with a retryable
+// error and may be retried, up to the number of attempts set with
+// WithRetry. It returns the last error of fn, or ctx.Err() if ctx is done
+// while waiting to retry.
+func (filer *Httpfs) retry(ctx context.Context, retryable bool, fn func() error) error {
+	backoff := time.Duration(0)
+	if filer.retries != nil {
+		backoff = filer.retries.backoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || err == io.EOF || !retryable || filer.retries == nil ||
+			attempt >= filer.retries.attempts || !filer.retries.retryable(err) {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryFile retries failed reads as set with WithRetry.
+type retryFile struct {
+	absfs.File
+	ctx   context.Context
+	filer *Httpfs
+}
+
+func (f *retryFile) Read(p []byte) (int, error) {
+	var n int
+	err := f.filer.retry(f.ctx, true, func() error {
+		var err error
+		n, err = f.File.Read(p)
+		if n > 0 {
+			// Return what was read: a persistent error recurs on the next
+			// Read.
+			return nil
+		}
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,116 @@
+package httpfs_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+var errFlaky = errors.New("connection reset")
+
+// flakyFS fails the first failOpens opens and the first failReads reads with
+// errFlaky.
+type flakyFS struct {
+	absfs.Filer
+
+	mu                   sync.Mutex
+	failOpens, failReads int
+	opens, reads         int
+}
+
+func (fs *flakyFS) fail(n *int) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if *n > 0 {
+		*n--
+		return true
+	}
+	return false
+}
+
+func (fs *flakyFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	fs.mu.Lock()
+	fs.opens++
+	fs.mu.Unlock()
+	if fs.fail(&fs.failOpens) {
+		return nil, errFlaky
+	}
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: f, fs: fs}, nil
+}
+
+type flakyFile struct {
+	absfs.File
+	fs *flakyFS
+}
+
+func (f *flakyFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	f.fs.reads++
+	f.fs.mu.Unlock()
+	if f.fs.fail(&f.fs.failReads) {
+		return 0, errFlaky
+	}
+	return f.File.Read(p)
+}
+
+func TestRetry(t *testing.T) {
+	ffs := &flakyFS{Filer: newMemFS(t, map[string]string{"/data.txt": "payload"})}
+	fs := httpfs.New(ffs, httpfs.WithRetry(3, time.Millisecond, nil))
+
+	ffs.failOpens, ffs.failReads = 2, 2
+	f, err := fs.Open("/data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "payload" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+	if ffs.opens != 3 {
+		t.Errorf("%d opens, want 3", ffs.opens)
+	}
+
+	// not-exist errors are not retried
+	ffs.opens = 0
+	if _, err := fs.Open("/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Open(/missing.txt) err = %v", err)
+	}
+	if ffs.opens != 1 {
+		t.Errorf("missing file opened %d times, want 1", ffs.opens)
+	}
+
+	// attempts run out
+	ffs.failOpens = 5
+	if _, err := fs.Open("/data.txt"); !errors.Is(err, errFlaky) {
+		t.Errorf("err = %v, want %v", err, errFlaky)
+	}
+	ffs.failOpens = 0
+
+	// waiting to retry gives up with the context
+	slow := httpfs.New(ffs, httpfs.WithRetry(3, time.Hour, nil))
+	ffs.failOpens = 1
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.OpenContext(ctx, "/data.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("OpenContext err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// a predicate rejecting the error disables retries
+	never := httpfs.New(ffs, httpfs.WithRetry(3, time.Millisecond, func(error) bool { return false }))
+	ffs.failOpens = 1
+	if _, err := never.Open("/data.txt"); !errors.Is(err, errFlaky) {
+		t.Errorf("err = %v, want %v", err, errFlaky)
+	}
+}
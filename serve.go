@@ -0,0 +1,328 @@
+package httpfs
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// indexPage is served in place of a directory listing when present.
+const indexPage = "index.html"
+
+// WithValidator sets a function computing the validators used for
+// conditional requests in place of the defaults. An empty etag or zero
+// lastMod falls back to the default for that validator.
+func WithValidator(validator func(name string, info os.FileInfo) (etag string, lastMod time.Time)) Option {
+	return func(filer *Httpfs) {
+		filer.validator = validator
+	}
+}
+
+// WithByteServing enables or disables range requests, which are enabled by
+// default. With byte serving disabled the Range header is ignored, files are
+// always sent whole with 200 OK and Accept-Ranges is not advertised.
+func WithByteServing(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.noByteServing = !enabled
+	}
+}
+
+// serve answers a GET or HEAD request for the file or directory named by the
+// request path.
+func (filer *Httpfs) serve(w http.ResponseWriter, r *http.Request) {
+	filer.serveCompressed(w, r, filer.compression)
+}
+
+// serveCompressed serves a GET or HEAD request as serve does, compressing the
+// response with c unless it is nil.
+func (filer *Httpfs) serveCompressed(w http.ResponseWriter, r *http.Request, c *compressor) {
+	w, done := filer.wrapWriter(w, r, c)
+	defer done()
+	filer.serveFile(w, r, path.Clean("/"+r.URL.Path))
+}
+
+// wrapWriter wraps w to compress the response to r with c, unless c is nil,
+// and to add the default charset. done must be called once the response is
+// written.
+func (filer *Httpfs) wrapWriter(w http.ResponseWriter, r *http.Request, c *compressor) (ww http.ResponseWriter, done func()) {
+	done = func() {}
+	if c != nil {
+		cw := c.wrap(w, r)
+		done = func() { cw.Close() }
+		w = cw
+	}
+	if filer.charset != "" {
+		w = &headerWriter{ResponseWriter: w, before: filer.addCharset}
+	}
+	return w, done
+}
+
+// ServeFile serves the file name in response to r, whatever the request path,
+// as ServeHTTP serves files: with support for range and conditional requests
+// and the headers configured by the options. It answers 404 Not Found if
+// name does not exist and 403 Forbidden if it is a directory.
+func (filer *Httpfs) ServeFile(w http.ResponseWriter, r *http.Request, name string) {
+	w, done := filer.wrapWriter(w, r, filer.compression)
+	defer done()
+	if filer.serveHead(w, r, name) {
+		return
+	}
+
+	f, err := filer.OpenContext(r.Context(), name)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	if info.IsDir() {
+		filer.serveStatus(w, r, http.StatusForbidden, nil)
+		return
+	}
+	filer.serveContent(w, r, name, info, f)
+}
+
+// ServeDownload serves the file name as ServeFile does, as an attachment to
+// be saved under downloadName, or the base name of name if empty, rather
+// than shown inline. The Content-Disposition header carries downloadName
+// with its non-ASCII characters replaced for old clients, and in full,
+// encoded as RFC 5987 describes, for the others. It is only sent with
+// successful responses.
+func (filer *Httpfs) ServeDownload(w http.ResponseWriter, r *http.Request, name, downloadName string) {
+	if downloadName == "" {
+		downloadName = path.Base(path.Clean("/" + name))
+	}
+	disposition := attachment(downloadName)
+	w = &headerWriter{ResponseWriter: w, before: func(code int, h http.Header) {
+		if code == http.StatusOK || code == http.StatusPartialContent || code == http.StatusNotModified {
+			h.Set("Content-Disposition", disposition)
+		}
+	}}
+	filer.ServeFile(w, r, name)
+}
+
+// attachment returns the Content-Disposition of an attachment named
+// filename.
+func attachment(filename string) string {
+	var ascii, encoded strings.Builder
+	plain := true
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		switch {
+		case c < ' ' || c >= 0x7f:
+			plain = false
+			if c < 0x80 || utf8.RuneStart(c) {
+				ascii.WriteByte('_')
+			}
+		case c == '"' || c == '\\':
+			ascii.WriteByte('\\')
+			ascii.WriteByte(c)
+		default:
+			ascii.WriteByte(c)
+		}
+		if attrChar(c) {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	if plain {
+		return `attachment; filename="` + ascii.String() + `"`
+	}
+	return `attachment; filename="` + ascii.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// attrChar reports whether c may appear unencoded in an RFC 5987 value.
+func attrChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// serveFile serves the file or directory name, redirecting requests for
+// directories to paths ending in a slash and requests for files away from
+// them, as http.FileServer does.
+func (filer *Httpfs) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	url := r.URL.Path
+	if strings.HasSuffix(url, "/"+indexPage) {
+		localRedirect(w, r, "./")
+		return
+	}
+	if !strings.HasSuffix(url, "/") && !filer.authorizeFiles && filer.serveHead(w, r, name) {
+		return
+	}
+
+	f, err := filer.OpenContext(r.Context(), name)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(url, "/") {
+			localRedirect(w, r, path.Base(url)+"/")
+			return
+		}
+		index := path.Join(name, indexPage)
+		ff, err := filer.OpenContext(r.Context(), index)
+		if err == nil {
+			defer ff.Close()
+			dd, err := ff.Stat()
+			if err == nil && !dd.IsDir() {
+				name, info, f = index, dd, ff
+			}
+		}
+	} else if strings.HasSuffix(url, "/") {
+		localRedirect(w, r, "../"+path.Base(url))
+		return
+	}
+
+	if info.IsDir() {
+		if !filer.mayList(r, name) {
+			filer.serveStatus(w, r, http.StatusForbidden, nil)
+			return
+		}
+		filer.serveListing(w, r, name)
+		return
+	}
+	if filer.authorizeFiles && !filer.authorizeListing(r, path.Dir(name)) {
+		filer.serveStatus(w, r, http.StatusForbidden, nil)
+		return
+	}
+	filer.serveContent(w, r, name, info, f)
+}
+
+// serveContent serves the contents of the file name, opened as f, whose
+// FileInfo is info.
+func (filer *Httpfs) serveContent(w http.ResponseWriter, r *http.Request, name string, info os.FileInfo, f http.File) {
+	if filer.contentType != nil {
+		if ctype := filer.contentType(name, info); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+	}
+	if filer.sidecars {
+		if gz, gzinfo := filer.openSidecar(w, r, name, f); gz != nil {
+			defer gz.Close()
+			f, info = gz, gzinfo
+		}
+	}
+
+	modTime := info.ModTime()
+	if filer.etag {
+		w.Header().Set("ETag", weakETag(info))
+	}
+	if filer.strongETag != nil {
+		if sum, ok := filer.strongETag.sum(name, info, f); ok {
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum)+`"`)
+		}
+	}
+	if filer.validator != nil {
+		etag, lastMod := filer.validator(name, info)
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if !lastMod.IsZero() {
+			modTime = lastMod
+		}
+	}
+	if filer.digest != nil {
+		filer.digest.setHeader(w.Header(), name, info, f)
+	}
+	if filer.noByteServing {
+		r = withoutRanges(r)
+		w = &headerWriter{ResponseWriter: w, before: func(code int, h http.Header) {
+			h.Del("Accept-Ranges")
+		}}
+	}
+	http.ServeContent(w, r, info.Name(), modTime, f)
+}
+
+// withoutRanges returns a shallow copy of r without its Range header.
+func withoutRanges(r *http.Request) *http.Request {
+	if r.Header.Get("Range") == "" {
+		return r
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = r.Header.Clone()
+	r2.Header.Del("Range")
+	return r2
+}
+
+// WithErrorHandler sets a function answering the requests that fail, in
+// place of the default plain text responses, for example to render custom
+// error pages. It is called with the response status, such as 404 Not Found
+// for files that do not exist or 403 Forbidden for permission errors and
+// refused directory listings, and the error, which may be nil.
+func WithErrorHandler(handler func(w http.ResponseWriter, r *http.Request, status int, err error)) Option {
+	return func(filer *Httpfs) {
+		filer.errorHandler = handler
+	}
+}
+
+// serveError answers r with the HTTP status matching err.
+func (filer *Httpfs) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	filer.serveStatus(w, r, errorStatus(err), err)
+}
+
+// serveStatus answers r with the error status, caused by err if not nil,
+// with the error handler if one is set.
+func (filer *Httpfs) serveStatus(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if filer.errorHandler != nil {
+		filer.errorHandler(w, r, status, err)
+		return
+	}
+	if status == http.StatusNotFound {
+		http.Error(w, "404 page not found", status)
+		return
+	}
+	http.Error(w, strconv.Itoa(status)+" "+http.StatusText(status), status)
+}
+
+// errorStatus returns the HTTP status matching err.
+func errorStatus(err error) int {
+	switch {
+	case os.IsNotExist(err):
+		return http.StatusNotFound
+	case os.IsPermission(err), errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, syscall.EISDIR), errors.Is(err, syscall.ENOTDIR):
+		return http.StatusConflict
+	case errors.Is(err, ErrBusy):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case tooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// localRedirect redirects to newPath relative to the request, keeping the
+// query string.
+func localRedirect(w http.ResponseWriter, r *http.Request, newPath string) {
+	if q := r.URL.RawQuery; q != "" {
+		newPath += "?" + q
+	}
+	w.Header().Set("Location", newPath)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
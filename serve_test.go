@@ -0,0 +1,272 @@
+package httpfs_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+func TestServeRedirects(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/site/index.html": "home",
+		"/site/a.txt":      "a",
+	}))
+
+	tests := []struct {
+		url      string
+		status   int
+		location string
+		body     string
+	}{
+		{"/site", http.StatusMovedPermanently, "site/", ""},
+		{"/site/", http.StatusOK, "", "home"},
+		{"/site/index.html", http.StatusMovedPermanently, "./", ""},
+		{"/site/a.txt/", http.StatusMovedPermanently, "../a.txt", ""},
+		{"/site/a.txt", http.StatusOK, "", "a"},
+		{"/missing.txt", http.StatusNotFound, "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: status = %d, want %d", test.url, w.Code, test.status)
+		}
+		if loc := w.Header().Get("Location"); loc != test.location {
+			t.Errorf("%s: Location = %q, want %q", test.url, loc, test.location)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: body = %q, want %q", test.url, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	version := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+	validator := func(name string, info os.FileInfo) (string, time.Time) {
+		if name == "/app.js" {
+			return `"v42"`, version
+		}
+		return "", time.Time{}
+	}
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/app.js":    "console.log(42)",
+		"/plain.txt": "plain",
+	}), httpfs.WithValidator(validator))
+
+	get := func(name string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", name, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/app.js", nil)
+	if etag := w.Header().Get("ETag"); etag != `"v42"` {
+		t.Errorf("ETag = %q", etag)
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != version.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+
+	w = get("/app.js", map[string]string{"If-None-Match": `"v42"`})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("matching If-None-Match: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	w = get("/app.js", map[string]string{"If-None-Match": `"v41"`})
+	if w.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = get("/plain.txt", nil)
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("fallback ETag = %q, want none", etag)
+	}
+	if w.Header().Get("Last-Modified") == "" {
+		t.Error("fallback missing Last-Modified")
+	}
+}
+
+func TestByteServingDisabled(t *testing.T) {
+	data := "0123456789"
+	for _, enabled := range []bool{true, false} {
+		fs := httpfs.New(newMemFS(t, map[string]string{"/digits.txt": data}), httpfs.WithByteServing(enabled))
+
+		req := httptest.NewRequest("GET", "/digits.txt", nil)
+		req.Header.Set("Range", "bytes=2-4")
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, req)
+
+		if enabled {
+			if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+				t.Errorf("enabled: status = %d, body = %q", w.Code, w.Body.String())
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || w.Body.String() != data {
+			t.Errorf("disabled: status = %d, body = %q", w.Code, w.Body.String())
+		}
+		if ar := w.Header().Get("Accept-Ranges"); ar != "" {
+			t.Errorf("disabled: Accept-Ranges = %q", ar)
+		}
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/private/report.txt": "0123456789",
+	}))
+	serve := func(name, rng string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/download", nil)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		w := httptest.NewRecorder()
+		fs.ServeFile(w, req, name)
+		return w
+	}
+
+	w := serve("/private/report.txt", "")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("GET = %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	w = serve("/private/report.txt", "bytes=2-5")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("ranged GET: status = %d, want %d", w.Code, http.StatusPartialContent)
+	}
+	if w.Body.String() != "2345" {
+		t.Errorf("ranged GET: body = %q, want %q", w.Body.String(), "2345")
+	}
+	if cr := w.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+		t.Errorf("Content-Range = %q", cr)
+	}
+
+	if w := serve("/private/missing.txt", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing file: status = %d", w.Code)
+	}
+	if w := serve("/private", ""); w.Code != http.StatusForbidden {
+		t.Errorf("directory: status = %d", w.Code)
+	}
+}
+
+// permFS refuses to open the files in denied.
+type permFS struct {
+	absfs.Filer
+	denied map[string]bool
+}
+
+func (fs *permFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if fs.denied[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func TestErrorHandler(t *testing.T) {
+	type call struct {
+		status int
+		path   string
+	}
+	var got []call
+	fs := httpfs.New(&permFS{
+		Filer: newMemFS(t, map[string]string{
+			"/private.txt": "private",
+			"/dir/a.txt":   "a",
+		}),
+		denied: map[string]bool{"/private.txt": true},
+	},
+		httpfs.WithListingAuthorizer(func(r *http.Request, dir string) bool { return false }),
+		httpfs.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+			got = append(got, call{status, r.URL.Path})
+			w.WriteHeader(status)
+			w.Write([]byte("custom"))
+		}),
+	)
+
+	for _, tt := range []call{
+		{http.StatusNotFound, "/missing.txt"},
+		{http.StatusForbidden, "/private.txt"},
+		{http.StatusForbidden, "/dir/"},
+	} {
+		got = nil
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status || w.Body.String() != "custom" {
+			t.Errorf("GET %s = %d %q, want %d from the error handler", tt.path, w.Code, w.Body, tt.status)
+		}
+		if len(got) != 1 || got[0] != tt {
+			t.Errorf("GET %s: error handler called with %v, want %v", tt.path, got, tt)
+		}
+	}
+
+	handler := httpfs.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		got = append(got, call{status, r.URL.Path})
+		w.WriteHeader(status)
+		w.Write([]byte("custom"))
+	})
+	files := map[string]string{"/dir/a.txt": "a"}
+	for _, tt := range []struct {
+		fs     *httpfs.Httpfs
+		method string
+		want   call
+	}{
+		{httpfs.New(&partialReaddirFS{newMemFS(t, files)}, handler), "GET", call{http.StatusInternalServerError, "/dir/"}},
+		{httpfs.New(newMemFS(t, files), handler, httpfs.WithListingTemplate(template.Must(template.New("").Parse("{{.Missing}}")))), "GET", call{http.StatusInternalServerError, "/dir/"}},
+		{httpfs.New(newMemFS(t, files), handler), "DELETE", call{http.StatusForbidden, "/"}},
+	} {
+		got = nil
+		w := httptest.NewRecorder()
+		tt.fs.ServeHTTP(w, httptest.NewRequest(tt.method, tt.want.path, nil))
+		if w.Code != tt.want.status || w.Body.String() != "custom" || len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s %s = %d %q, error handler called with %v, want %v", tt.method, tt.want.path, w.Code, w.Body, got, tt.want)
+		}
+	}
+}
+
+func TestServeDownload(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/files/cv.pdf":     "%PDF",
+		"/files/report.txt": "report",
+	}))
+	download := func(name, downloadName string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		fs.ServeDownload(w, httptest.NewRequest("GET", "/download", nil), name, downloadName)
+		return w
+	}
+
+	w := download("/files/cv.pdf", "résumé 2024.pdf")
+	if w.Code != http.StatusOK || w.Body.String() != "%PDF" {
+		t.Errorf("GET = %d %q", w.Code, w.Body.String())
+	}
+	want := `attachment; filename="r_sum_ 2024.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.pdf`
+	if cd := w.Header().Get("Content-Disposition"); cd != want {
+		t.Errorf("Content-Disposition = %q, want %q", cd, want)
+	}
+
+	w = download("/files/report.txt", `the "final" report.txt`)
+	want = `attachment; filename="the \"final\" report.txt"`
+	if cd := w.Header().Get("Content-Disposition"); cd != want {
+		t.Errorf("Content-Disposition = %q, want %q", cd, want)
+	}
+	w = download("/files/report.txt", "")
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="report.txt"` {
+		t.Errorf("default Content-Disposition = %q", cd)
+	}
+
+	w = download("/files/missing.txt", "missing.txt")
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("missing file: %d with Content-Disposition %q", w.Code, w.Header().Get("Content-Disposition"))
+	}
+}
@@ -0,0 +1,239 @@
+package httpfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/absfs/absfs"
+)
+
+// snapshotter is implemented by filers that can take a snapshot of their
+// contents, which must not change as the filer does.
+type snapshotter interface {
+	Snapshot() (absfs.Filer, error)
+}
+
+// Snapshot returns a read-only Httpfs serving the contents of the underlying
+// filer as they are now, so that a consistent tree can be served while the
+// filer is rewritten. The snapshot is configured with the options filer was
+// created with, so that it is restricted and serves names as filer does, and
+// then with opts. It shares the concurrency limit of filer, and the locks of
+// WithLocking if filer has them.
+//
+// If the underlying filer has a method Snapshot() (absfs.Filer, error), the
+// snapshot serves the filer it returns. Otherwise the snapshot is best
+// effort: each file is frozen as it is first found, its contents as it is
+// first opened and the entries of each directory as it is first read, so
+// that changes made to the filer before then are seen, and changes made
+// after are not. Files not found are looked up again each time. With
+// WithLocking, a file is read whole under the lock of its path, so that the
+// contents frozen are not those of a write in progress. The frozen contents
+// of every file opened are held in memory for as long as the snapshot is in
+// use, so trees too large for that need a filer with snapshots.
+func (filer *Httpfs) Snapshot(opts ...Option) (*Httpfs, error) {
+	var view absfs.Filer
+	if s, ok := filer.fs.(snapshotter); ok {
+		var err error
+		view, err = s.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		view = &frozenFS{fs: filer.fs, entries: make(map[string]*frozenEntry)}
+	}
+	snap := New(view, append(filer.opts[:len(filer.opts):len(filer.opts)], opts...)...)
+	snap.readOnly = true
+	snap.sem, snap.semTimeout = filer.sem, filer.semTimeout
+	if filer.locks != nil {
+		snap.locks = filer.locks
+	}
+	return snap, nil
+}
+
+// frozenFS is the best effort snapshot of a filer without snapshots. It
+// keeps the FileInfo of each file found, the contents of each file opened
+// and the entries of each directory read.
+type frozenFS struct {
+	fs absfs.Filer
+
+	mu      sync.Mutex
+	entries map[string]*frozenEntry
+}
+
+// frozenEntry is the frozen state of a file.
+type frozenEntry struct {
+	info os.FileInfo
+	err  error
+
+	mu    sync.Mutex
+	data  []byte
+	infos []os.FileInfo
+	read  bool
+}
+
+// entry returns the frozen entry for name, freezing it if it exists.
+func (f *frozenFS) entry(name string) *frozenEntry {
+	name = path.Clean("/" + name)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.entries[name]; ok {
+		return e
+	}
+	info, err := f.fs.Stat(name)
+	e := &frozenEntry{info: info, err: err}
+	if err == nil {
+		f.entries[name] = e
+	}
+	return e
+}
+
+// freeze records info for the file name unless it was looked up already.
+func (f *frozenFS) freeze(name string, info os.FileInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.entries[name]; !ok {
+		f.entries[name] = &frozenEntry{info: info}
+	}
+}
+
+func (f *frozenFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if isWrite(flag) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	e := f.entry(name)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.read {
+		var err error
+		if e.info.IsDir() {
+			err = f.readDir(e, name)
+		} else {
+			err = f.readFile(e, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		e.read = true
+	}
+	return &frozenFile{Reader: bytes.NewReader(e.data), name: name, info: e.info, infos: e.infos}, nil
+}
+
+// readDir freezes the entries of the directory name, and the FileInfo of
+// those not looked up yet.
+func (f *frozenFS) readDir(e *frozenEntry, name string) error {
+	infos, err := readdirAll(f.fs, name)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		f.freeze(path.Join("/", name, info.Name()), info)
+	}
+	e.infos = infos
+	return nil
+}
+
+// readFile freezes the contents of the file name.
+func (f *frozenFS) readFile(e *frozenEntry, name string) error {
+	file, err := f.fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	e.data, err = io.ReadAll(file)
+	return err
+}
+
+func (f *frozenFS) Stat(name string) (os.FileInfo, error) {
+	e := f.entry(name)
+	return e.info, e.err
+}
+
+func (f *frozenFS) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+func (f *frozenFS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (f *frozenFS) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrReadOnly}
+}
+
+func (f *frozenFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: ErrReadOnly}
+}
+
+func (f *frozenFS) Chown(name string, uid, gid int) error {
+	return &os.PathError{Op: "chown", Path: name, Err: ErrReadOnly}
+}
+
+// frozenFile is a file opened in a frozenFS.
+type frozenFile struct {
+	*bytes.Reader
+	name  string
+	info  os.FileInfo
+	infos []os.FileInfo
+}
+
+func (f *frozenFile) Name() string { return f.name }
+
+func (f *frozenFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *frozenFile) Readdir(n int) ([]os.FileInfo, error) {
+	if !f.info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+	if n <= 0 {
+		infos := f.infos
+		f.infos = nil
+		return infos, nil
+	}
+	if len(f.infos) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.infos) {
+		n = len(f.infos)
+	}
+	infos := f.infos[:n]
+	f.infos = f.infos[n:]
+	return infos, nil
+}
+
+func (f *frozenFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+func (f *frozenFile) Close() error { return nil }
+
+func (f *frozenFile) Sync() error { return nil }
+
+func (f *frozenFile) Write(b []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *frozenFile) WriteAt(b []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *frozenFile) WriteString(s string) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
+
+func (f *frozenFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: f.name, Err: ErrReadOnly}
+}
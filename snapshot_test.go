@@ -0,0 +1,176 @@
+package httpfs_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+	"github.com/absfs/memfs"
+)
+
+// snapshotFS takes snapshots by copying its files to a new memfs.
+type snapshotFS struct {
+	absfs.Filer
+	snapshots int
+}
+
+func (fs *snapshotFS) Snapshot() (absfs.Filer, error) {
+	fs.snapshots++
+	var buf bytes.Buffer
+	if err := httpfs.New(fs.Filer).Tar(&buf, "/"); err != nil {
+		return nil, err
+	}
+	mfs, err := memfs.NewFS()
+	if err != nil {
+		return nil, err
+	}
+	return mfs, httpfs.New(mfs).Untar(&buf, "/")
+}
+
+func writeFile(t *testing.T, fs *httpfs.Httpfs, name, data string) {
+	t.Helper()
+	f, err := fs.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	sfs := &snapshotFS{Filer: newMemFS(t, map[string]string{"/site/index.html": "v1"})}
+	fs := httpfs.New(sfs)
+
+	snap, err := fs.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sfs.snapshots != 1 {
+		t.Errorf("%d snapshots taken, want 1", sfs.snapshots)
+	}
+	writeFile(t, fs, "/site/index.html", "v2")
+	writeFile(t, fs, "/site/new.html", "new")
+
+	if got := readFile(t, snap, "/site/index.html"); got != "v1" {
+		t.Errorf("snapshot index.html = %q, want v1", got)
+	}
+	if _, err := snap.Stat("/site/new.html"); !os.IsNotExist(err) {
+		t.Errorf("snapshot sees a file created later: %v", err)
+	}
+	if _, err := snap.Create("/site/other.html"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("write to snapshot: err = %v", err)
+	}
+}
+
+func TestSnapshotFallback(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/site/index.html": "v1",
+		"/site/about.html": "about",
+	}))
+
+	snap, err := fs.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// files are frozen as they are first read
+	if got := readFile(t, snap, "/site/index.html"); got != "v1" {
+		t.Fatalf("index.html = %q", got)
+	}
+	entries, err := snap.ReadDir("/site")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ReadDir = %v, %v", entries, err)
+	}
+
+	writeFile(t, fs, "/site/index.html", "v2")
+	writeFile(t, fs, "/site/new.html", "new")
+	if err := fs.Remove("/site/about.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, snap, "/site/index.html"); got != "v1" {
+		t.Errorf("index.html = %q after a later write, want v1", got)
+	}
+	entries, err = snap.ReadDir("/site")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "about.html" {
+		t.Errorf("ReadDir after later writes = %v, %v", entries, err)
+	}
+	if err := snap.Remove("/site/index.html"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Remove from snapshot: err = %v", err)
+	}
+
+	// Files not found yet are frozen once they are.
+	if _, err := snap.Stat("/site/later.html"); !os.IsNotExist(err) {
+		t.Fatalf("Stat of a missing file = %v", err)
+	}
+	writeFile(t, fs, "/site/later.html", "later")
+	if got := readFile(t, snap, "/site/later.html"); got != "later" {
+		t.Errorf("later.html = %q", got)
+	}
+}
+
+// blockingWriteFS blocks opening files for writing until release is closed,
+// closing started when it does.
+type blockingWriteFS struct {
+	absfs.Filer
+	started, release chan struct{}
+}
+
+func (fs *blockingWriteFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		close(fs.started)
+		<-fs.release
+	}
+	return fs.Filer.OpenFile(name, flag, perm)
+}
+
+func TestSnapshotFallbackLocking(t *testing.T) {
+	bfs := &blockingWriteFS{
+		Filer:   newMemFS(t, map[string]string{"/a.txt": "a"}),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	fs := httpfs.New(bfs, httpfs.WithLocking(true))
+	snap, err := fs.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrote := make(chan struct{})
+	go func() {
+		defer close(wrote)
+		f, err := fs.Create("/a.txt")
+		if err == nil {
+			f.Close()
+		}
+	}()
+	<-bfs.started
+	frozen := make(chan error)
+	go func() {
+		_, err := snap.Stat("/a.txt")
+		if err == nil {
+			var f http.File
+			f, err = snap.Open("/a.txt")
+			if err == nil {
+				f.Close()
+			}
+		}
+		frozen <- err
+	}()
+	select {
+	case err := <-frozen:
+		t.Fatalf("snapshot froze a.txt while it was being opened for writing: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(bfs.release)
+	<-wrote
+	if err := <-frozen; err != nil {
+		t.Fatal(err)
+	}
+}
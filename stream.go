@@ -0,0 +1,120 @@
+package httpfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/absfs/absfs"
+)
+
+// streamChunkSize is the size of the chunks StreamFile copies between
+// context checks.
+const streamChunkSize = 32 * 1024
+
+// StreamFile copies the named file to w in chunks, checking ctx between
+// chunks. If ctx is done the copy stops and ctx.Err() is returned along with
+// the number of bytes copied so far.
+func (filer *Httpfs) StreamFile(ctx context.Context, w io.Writer, name string) (int64, error) {
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, streamChunkSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		nr, rerr := f.Read(buf)
+		if nr > 0 {
+			nw, werr := w.Write(buf[:nr])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// OpenReadSeeker opens the named file for reading, for use with
+// http.ServeContent and other readers that seek. The first time a seek fails
+// because the underlying file cannot seek, the whole file is read again into
+// memory and served from there, so a non-seekable file costs as much memory
+// as its size once seeked.
+func (filer *Httpfs) OpenReadSeeker(name string) (io.ReadSeekCloser, error) {
+	f, err := filer.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &readSeeker{file: f, filer: filer, name: name}, nil
+}
+
+// readSeeker is the file returned by OpenReadSeeker. Once buf is set the
+// file is served from it.
+type readSeeker struct {
+	file  absfs.File
+	filer *Httpfs
+	name  string
+	pos   int64
+	buf   *bytes.Reader
+}
+
+var errNegativeOffset = errors.New("negative offset")
+
+func (f *readSeeker) Read(p []byte) (int, error) {
+	if f.buf != nil {
+		return f.buf.Read(p)
+	}
+	n, err := f.file.Read(p)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *readSeeker) Seek(offset int64, whence int) (int64, error) {
+	if f.buf != nil {
+		return f.buf.Seek(offset, whence)
+	}
+	switch {
+	case whence == io.SeekStart && offset < 0, whence == io.SeekCurrent && f.pos+offset < 0:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: errNegativeOffset}
+	case whence != io.SeekStart && whence != io.SeekCurrent && whence != io.SeekEnd:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	pos, err := f.file.Seek(offset, whence)
+	if err == nil {
+		f.pos = pos
+		return pos, nil
+	}
+
+	data, err := f.filer.ReadFile(f.name)
+	if err != nil {
+		return 0, err
+	}
+	f.file.Close()
+	f.buf = bytes.NewReader(data)
+	if _, err := f.buf.Seek(f.pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return f.buf.Seek(offset, whence)
+}
+
+func (f *readSeeker) Close() error {
+	if f.buf != nil {
+		return nil
+	}
+	return f.file.Close()
+}
@@ -0,0 +1,115 @@
+package httpfs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// cancelWriter cancels its context once more than after bytes are written.
+type cancelWriter struct {
+	bytes.Buffer
+	after  int
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	if w.Len() > w.after {
+		w.cancel()
+	}
+	return n, err
+}
+
+func TestStreamFile(t *testing.T) {
+	data := strings.Repeat("0123456789abcdef", 64*1024)
+	fs := httpfs.New(newMemFS(t, map[string]string{"/big.bin": data}))
+
+	var buf bytes.Buffer
+	n, err := fs.StreamFile(context.Background(), &buf, "/big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || buf.String() != data {
+		t.Fatalf("copied %d bytes, want %d", n, len(data))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{after: len(data) / 4, cancel: cancel}
+	n, err = fs.StreamFile(ctx, w, "/big.bin")
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if n == 0 || n >= int64(len(data)) || n != int64(w.Len()) {
+		t.Errorf("copied %d bytes (writer has %d), want a partial count", n, w.Len())
+	}
+}
+
+// noSeekFS opens files whose Seek always fails, like pipes or network
+// streams.
+type noSeekFS struct {
+	absfs.Filer
+}
+
+func (fs *noSeekFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return noSeekFile{f}, nil
+}
+
+type noSeekFile struct {
+	absfs.File
+}
+
+func (noSeekFile) Seek(int64, int) (int64, error) {
+	return 0, errors.New("seek not supported")
+}
+
+func TestOpenReadSeeker(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		fs   absfs.Filer
+	}{
+		{"seekable", newMemFS(t, map[string]string{"/a.txt": "0123456789"})},
+		{"not seekable", &noSeekFS{newMemFS(t, map[string]string{"/a.txt": "0123456789"})}},
+	} {
+		fs := httpfs.New(tt.fs)
+		f, err := fs.OpenReadSeeker("/a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, 3)
+		if _, err := io.ReadFull(f, buf); err != nil || string(buf) != "012" {
+			t.Errorf("%s: first read = %q, %v", tt.desc, buf, err)
+		}
+		if pos, err := f.Seek(2, io.SeekCurrent); err != nil || pos != 5 {
+			t.Errorf("%s: Seek(2, current) = %d, %v", tt.desc, pos, err)
+		}
+		if _, err := io.ReadFull(f, buf); err != nil || string(buf) != "567" {
+			t.Errorf("%s: read after seek = %q, %v", tt.desc, buf, err)
+		}
+		if pos, err := f.Seek(-2, io.SeekEnd); err != nil || pos != 8 {
+			t.Errorf("%s: Seek(-2, end) = %d, %v", tt.desc, pos, err)
+		}
+		if rest, err := io.ReadAll(f); err != nil || string(rest) != "89" {
+			t.Errorf("%s: rest = %q, %v", tt.desc, rest, err)
+		}
+		if _, err := f.Seek(-1, io.SeekStart); err == nil {
+			t.Errorf("%s: Seek to a negative offset succeeded", tt.desc)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("%s: Close: %v", tt.desc, err)
+		}
+	}
+}
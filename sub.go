@@ -0,0 +1,151 @@
+package httpfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/absfs/absfs"
+)
+
+// Sub returns an fs.FS for the subtree rooted at dir. Besides fs.FS it
+// implements fs.StatFS, fs.ReadDirFS and fs.ReadFileFS, so that fs.WalkDir,
+// fs.Glob and http.FS need not fall back to opening every file. dir must be
+// a valid fs.FS path, with "." naming the root.
+func (filer *Httpfs) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &os.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	return &subFS{filer: filer, dir: path.Join("/", dir)}, nil
+}
+
+// FS returns an fs.FS of the whole filesystem, as Sub(".") does, for use with
+// template.ParseFS, http.FS, fstest.TestFS and the like.
+func (filer *Httpfs) FS() fs.FS {
+	return &subFS{filer: filer, dir: "/"}
+}
+
+// subFS is the fs.FS returned by Sub.
+type subFS struct {
+	filer *Httpfs
+	dir   string
+}
+
+// path returns the filer path of the fs.FS name, or an error for op if name
+// is not a valid fs.FS path. Names holding a backslash are invalid too unless
+// disabled with WithSlashPaths, as the filer would take it for a separator,
+// which an fs.FS must not.
+func (sub *subFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) || !sub.filer.noSlashPaths && strings.Contains(name, `\`) {
+		return "", &os.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(sub.dir, name), nil
+}
+
+// fix reports err, returned by the filer for an operation on name, with name
+// as its path, as the fs.FS interfaces require.
+func fix(name string, err error) error {
+	var perr *os.PathError
+	if errors.As(err, &perr) {
+		return &os.PathError{Op: perr.Op, Path: name, Err: perr.Err}
+	}
+	return err
+}
+
+func (sub *subFS) Open(name string) (fs.File, error) {
+	p, err := sub.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := sub.filer.OpenFile(p, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fix(name, err)
+	}
+	return &subFile{File: f}, nil
+}
+
+func (sub *subFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := sub.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sub.filer.Stat(p)
+	return info, fix(name, err)
+}
+
+func (sub *subFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := sub.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := sub.filer.ReadDir(p)
+	return entries, fix(name, err)
+}
+
+func (sub *subFS) ReadFile(name string) ([]byte, error) {
+	p, err := sub.path("read", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := sub.filer.ReadFile(p)
+	return data, fix(name, err)
+}
+
+// Glob implements fs.GlobFS with the filer's Glob, within the subtree.
+func (sub *subFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !fs.ValidPath(pattern) {
+		return nil, nil
+	}
+	matches, err := sub.filer.Glob(path.Join(escapeMeta(sub.dir), pattern))
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range matches {
+		m = strings.TrimPrefix(strings.TrimPrefix(m, sub.dir), "/")
+		if m == "" {
+			m = "."
+		}
+		matches[i] = m
+	}
+	return matches, nil
+}
+
+// escapeMeta escapes the characters of name that path.Match would take for
+// part of a pattern.
+func escapeMeta(name string) string {
+	if !hasMeta(name) {
+		return name
+	}
+	var b strings.Builder
+	for _, c := range name {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// subFile adds fs.ReadDirFile's ReadDir to an absfs.File. It keeps the
+// file's Seek and ReadAt, so that http.FS serves range requests from it.
+type subFile struct {
+	absfs.File
+}
+
+func (f *subFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := f.Readdir(n)
+	if n <= 0 && err == io.EOF {
+		err = nil
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, err
+}
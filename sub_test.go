@@ -0,0 +1,142 @@
+package httpfs_test
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestSub(t *testing.T) {
+	filer := httpfs.New(newMemFS(t, map[string]string{
+		"/outside.txt":         "outside",
+		"/site/index.html":     "index",
+		"/site/css/main.css":   "body{}",
+		"/site/js/app.js":      "app",
+		"/site/js/lib/util.js": "util",
+	}))
+	sub, err := filer.Sub("site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := sub.(fs.StatFS); !ok {
+		t.Error("Sub does not implement fs.StatFS")
+	}
+	if _, ok := sub.(fs.ReadDirFS); !ok {
+		t.Error("Sub does not implement fs.ReadDirFS")
+	}
+	if _, ok := sub.(fs.ReadFileFS); !ok {
+		t.Error("Sub does not implement fs.ReadFileFS")
+	}
+	if _, ok := sub.(fs.GlobFS); !ok {
+		t.Error("Sub does not implement fs.GlobFS")
+	}
+
+	if err := fstest.TestFS(sub, "index.html", "css/main.css", "js/app.js", "js/lib/util.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(sub, "js/lib/util.js")
+	if err != nil || string(data) != "util" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	info, err := fs.Stat(sub, "css/main.css")
+	if err != nil || info.Size() != 6 {
+		t.Errorf("Stat = %v, %v", info, err)
+	}
+	entries, err := fs.ReadDir(sub, "js")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "app.js" || !entries[1].IsDir() {
+		t.Errorf("ReadDir = %v, %v", entries, err)
+	}
+
+	if _, err := fs.Stat(sub, "../outside.txt"); err == nil {
+		t.Error("Stat outside the subtree succeeded")
+	}
+	if _, err := fs.Stat(sub, "outside.txt"); err == nil {
+		t.Error("Stat of a file outside the subtree succeeded")
+	}
+	if _, err := filer.Sub("/site"); err == nil {
+		t.Error("Sub accepted an invalid path")
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := httpfs.New(newMemFS(t, map[string]string{
+		"/index.html":   "index",
+		"/css/main.css": "body{}",
+	})).FS()
+
+	if _, ok := fsys.(fs.StatFS); !ok {
+		t.Error("FS does not implement fs.StatFS")
+	}
+	if _, ok := fsys.(fs.ReadDirFS); !ok {
+		t.Error("FS does not implement fs.ReadDirFS")
+	}
+	if err := fstest.TestFS(fsys, "index.html", "css/main.css"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubRangeRequest(t *testing.T) {
+	sub, err := httpfs.New(newMemFS(t, map[string]string{
+		"/public/data.txt": "0123456789",
+	})).Sub("public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := sub.Open("data.txt"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := f.(io.Seeker); !ok {
+		t.Error("files opened from Sub are not seekable")
+	}
+
+	r := httptest.NewRequest("GET", "/data.txt", nil)
+	r.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	http.FileServer(http.FS(sub)).ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("range request = %d %q, want 206 %q", w.Code, w.Body, "234")
+	}
+}
+
+func TestSubGlob(t *testing.T) {
+	filer := httpfs.New(newMemFS(t, map[string]string{
+		"/outside.html":              "",
+		"/[site]/index.html":         "",
+		"/[site]/about.html":         "",
+		"/[site]/tmpl/base.html":     "",
+		"/[site]/tmpl/partials.html": "",
+		"/[site]/tmpl/notes.txt":     "",
+	}))
+	sub, err := filer.Sub("[site]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pattern, want := range map[string][]string{
+		"*.html":      {"about.html", "index.html"},
+		"tmpl/*.html": {"tmpl/base.html", "tmpl/partials.html"},
+		"*/notes.txt": {"tmpl/notes.txt"},
+		"../*.html":   nil,
+		"missing/*":   nil,
+	} {
+		matches, err := fs.Glob(sub, pattern)
+		if err != nil {
+			t.Errorf("Glob(%q): %v", pattern, err)
+			continue
+		}
+		if len(matches) != len(want) || len(want) > 0 && !reflect.DeepEqual(matches, want) {
+			t.Errorf("Glob(%q) = %q, want %q", pattern, matches, want)
+		}
+	}
+	if _, err := fs.Glob(sub, "[*.html"); err != path.ErrBadPattern {
+		t.Errorf("bad pattern err = %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package httpfs
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotSupported is returned by operations the underlying filer does not
+// support, such as Symlink and Readlink on filers without symbolic links.
+var ErrNotSupported = errors.New("operation not supported")
+
+// symlinker is implemented by filers that can create symbolic links.
+type symlinker interface {
+	Symlink(oldname, newname string) error
+}
+
+// lstater is implemented by filers that can stat a symbolic link without
+// following it.
+type lstater interface {
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// readlinker is implemented by filers that can read the target of a symbolic
+// link.
+type readlinker interface {
+	Readlink(name string) (string, error)
+}
+
+// linker is implemented by filers that can create hard links.
+type linker interface {
+	Link(oldname, newname string) error
+}
+
+// WithAllowSymlinkCreation permits creating symbolic links. Link creation is
+// refused by default, even on filers that support links, so that written
+// content cannot link to files outside the tree.
+func WithAllowSymlinkCreation(allow bool) Option {
+	return func(filer *Httpfs) {
+		filer.allowSymlinks = allow
+	}
+}
+
+// Symlink creates newname as a symbolic link to oldname. Unless allowed with
+// WithAllowSymlinkCreation, or if the filesystem is read-only, it fails with
+// fs.ErrPermission. On filers without symbolic links it fails with
+// ErrNotSupported. newname cannot be hidden, and is checked as a write to it
+// would be.
+func (filer *Httpfs) Symlink(oldname, newname string) error {
+	if !filer.allowSymlinks || filer.readOnly {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrPermission}
+	}
+	s, ok := filer.fs.(symlinker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: ErrNotSupported}
+	}
+	if err := filer.checkNewLink("symlink", oldname, newname); err != nil {
+		return err
+	}
+	if err := filer.acquire(); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("symlink", newname)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, true)()
+	return s.Symlink(oldname, p)
+}
+
+// Link creates newname as a hard link to the file oldname. If the filesystem
+// is read-only it fails with fs.ErrPermission, and on filers without hard
+// links with ErrNotSupported. Hidden files cannot be linked, or linked to,
+// and newname is checked as a write to it would be, as Rename checks its
+// destination.
+func (filer *Httpfs) Link(oldname, newname string) error {
+	if filer.readOnly {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: fs.ErrPermission}
+	}
+	l, ok := filer.fs.(linker)
+	if !ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: ErrNotSupported}
+	}
+	if filer.hidden(oldname) {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if err := filer.checkNewLink("link", oldname, newname); err != nil {
+		return err
+	}
+	if err := filer.acquire(); err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	defer filer.release()
+	oldp, err := filer.resolve("link", oldname)
+	if err != nil {
+		return err
+	}
+	newp, err := filer.resolve("link", newname)
+	if err != nil {
+		return err
+	}
+	defer filer.lock2(oldp, newp)()
+	err = l.Link(oldp, newp)
+	switch err.(type) {
+	case nil, *os.LinkError, *os.PathError:
+		return err
+	}
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+}
+
+// checkNewLink returns an error for op if a link may not be created at
+// newname because it is hidden or may not be written.
+func (filer *Httpfs) checkNewLink(op, oldname, newname string) error {
+	if filer.hidden(newname) {
+		return &os.LinkError{Op: op, Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if err := filer.checkWrite(newname); err != nil {
+		return &os.LinkError{Op: op, Old: oldname, New: newname, Err: underlying(err)}
+	}
+	return nil
+}
+
+// Readlink returns the target of the symbolic link name. On filers without
+// symbolic links it fails with ErrNotSupported.
+func (filer *Httpfs) Readlink(name string) (string, error) {
+	r, ok := filer.fs.(readlinker)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: ErrNotSupported}
+	}
+	if err := filer.acquire(); err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return r.Readlink(p)
+}
+
+// Lstat returns the FileInfo of the file name without following it if it is
+// a symbolic link. On filers without symbolic links it is the same as Stat.
+func (filer *Httpfs) Lstat(name string) (os.FileInfo, error) {
+	if filer.hidden(name) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	return filer.lstat(name)
+}
+
+// lstat returns the FileInfo of the file name as Lstat does, hidden or not.
+func (filer *Httpfs) lstat(name string) (os.FileInfo, error) {
+	l, ok := filer.fs.(lstater)
+	if !ok {
+		return filer.stat(name)
+	}
+	if err := filer.acquire(); err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	defer filer.release()
+	p, err := filer.resolve("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	defer filer.lock(p, false)()
+	start := filer.startOp()
+	info, err := l.Lstat(p)
+	filer.endOp(opStat, name, start, err)
+	if err != nil {
+		return nil, pathError("lstat", name, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,290 @@
+package httpfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+func TestSymlinkCreationGuard(t *testing.T) {
+	sfs := &symlinkFS{
+		Filer: newMemFS(t, map[string]string{"/target.txt": "target"}),
+		links: map[string]string{},
+	}
+
+	err := httpfs.New(sfs).Symlink("/target.txt", "/link.txt")
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("err = %v, want %v", err, fs.ErrPermission)
+	}
+	if _, ok := sfs.links["/link.txt"]; ok {
+		t.Fatal("symlink created by default")
+	}
+
+	err = httpfs.New(sfs, httpfs.WithAllowSymlinkCreation(true)).Symlink("/target.txt", "/link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sfs.links["/link.txt"] != "/target.txt" {
+		t.Fatalf("links = %v", sfs.links)
+	}
+
+	restricted := httpfs.New(sfs,
+		httpfs.WithAllowSymlinkCreation(true),
+		httpfs.WithDenyGlobs("*.key"),
+		httpfs.WithAllowedExtensions(".txt", ".key"),
+		httpfs.WithHideDotfiles(true))
+	for name, want := range map[string]error{
+		"/secret.key": fs.ErrPermission,
+		"/tool.exe":   fs.ErrPermission,
+		"/.env.txt":   fs.ErrNotExist,
+	} {
+		if err := restricted.Symlink("/target.txt", name); !errors.Is(err, want) {
+			t.Errorf("Symlink at %s = %v, want %v", name, err, want)
+		}
+		if _, ok := sfs.links[name]; ok {
+			t.Errorf("Symlink at %s created the link", name)
+		}
+	}
+}
+
+func TestReadlink(t *testing.T) {
+	sfs := &symlinkFS{
+		Filer: newMemFS(t, map[string]string{"/target.txt": "target"}),
+		links: map[string]string{},
+	}
+	filer := httpfs.New(sfs, httpfs.WithAllowSymlinkCreation(true))
+	if err := filer.Symlink("/target.txt", "/link.txt"); err != nil {
+		t.Fatal(err)
+	}
+	target, err := filer.Readlink("/link.txt")
+	if err != nil || target != "/target.txt" {
+		t.Errorf("Readlink = %q, %v; want %q", target, err, "/target.txt")
+	}
+	if _, err := filer.Readlink("/target.txt"); err == nil {
+		t.Error("Readlink of a regular file succeeded")
+	}
+}
+
+func TestSymlinkNotSupported(t *testing.T) {
+	filer := httpfs.New(newMemFS(t, map[string]string{"/target.txt": "target"}), httpfs.WithAllowSymlinkCreation(true))
+
+	if err := filer.Symlink("/target.txt", "/link.txt"); !errors.Is(err, httpfs.ErrNotSupported) {
+		t.Errorf("Symlink: err = %v, want %v", err, httpfs.ErrNotSupported)
+	}
+	if _, err := filer.Readlink("/target.txt"); !errors.Is(err, httpfs.ErrNotSupported) {
+		t.Errorf("Readlink: err = %v, want %v", err, httpfs.ErrNotSupported)
+	}
+}
+
+func TestLstat(t *testing.T) {
+	sfs := &symlinkFS{
+		Filer: newMemFS(t, map[string]string{"/target.txt": "target", "/link.txt": "target"}),
+		links: map[string]string{"/link.txt": "/target.txt"},
+	}
+	filer := httpfs.New(sfs)
+
+	info, err := filer.Lstat("/link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat mode = %v, want a symlink", info.Mode())
+	}
+	info, err = filer.Stat("/link.txt")
+	if err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		t.Errorf("Stat = %v, %v; want the followed file", info, err)
+	}
+	info, err = filer.Lstat("/target.txt")
+	if err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Lstat of a regular file = %v, %v", info, err)
+	}
+
+	plain := httpfs.New(newMemFS(t, map[string]string{"/file.txt": "data"}))
+	info, err = plain.Lstat("/file.txt")
+	if err != nil || info.Size() != 4 {
+		t.Errorf("Lstat fallback = %v, %v", info, err)
+	}
+}
+
+// dirLinkFS presents the placeholder file link as a symbolic link to the
+// directory target, which Stat and OpenFile follow.
+type dirLinkFS struct {
+	absfs.Filer
+	link, target string
+}
+
+func (fs *dirLinkFS) follow(name string) string {
+	if name == fs.link || strings.HasPrefix(name, fs.link+"/") {
+		return fs.target + name[len(fs.link):]
+	}
+	return name
+}
+
+func (fs *dirLinkFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	return fs.Filer.OpenFile(fs.follow(name), flag, perm)
+}
+
+func (fs *dirLinkFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Filer.Stat(fs.follow(name))
+}
+
+func (fs *dirLinkFS) Lstat(name string) (os.FileInfo, error) {
+	if name == fs.link {
+		return &fileInfo{name: path.Base(name), mode: os.ModeSymlink | 0777}, nil
+	}
+	return fs.Filer.Stat(fs.follow(name))
+}
+
+func (fs *dirLinkFS) Remove(name string) error {
+	if name == fs.link {
+		return fs.Filer.Remove(name)
+	}
+	return fs.Filer.Remove(fs.follow(name))
+}
+
+func TestRemoveAllSymlinkedDir(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{
+		"/data/keep.txt": "keep",
+		"/tree/own.txt":  "own",
+		"/tree/link":     "",
+	})
+	filer := httpfs.New(&dirLinkFS{Filer: mfs, link: "/tree/link", target: "/data"})
+
+	if info, err := filer.Stat("/tree/link"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat of the link = %v, %v; want the target directory", info, err)
+	}
+	if err := filer.RemoveAll("/tree"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"/tree", "/tree/link"} {
+		if _, err := mfs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", name, err)
+		}
+	}
+	if _, err := mfs.Stat("/data/keep.txt"); err != nil {
+		t.Errorf("link target contents removed: %v", err)
+	}
+}
+
+// linkFS adds hard links to a filer by recording them.
+type linkFS struct {
+	absfs.Filer
+	links map[string]string
+}
+
+func (fs *linkFS) Link(oldname, newname string) error {
+	if _, err := fs.Stat(oldname); err != nil {
+		return err
+	}
+	fs.links[newname] = oldname
+	return nil
+}
+
+func TestLink(t *testing.T) {
+	lfs := &linkFS{Filer: newMemFS(t, map[string]string{"/a.txt": "a"}), links: map[string]string{}}
+	if err := httpfs.New(lfs).Link("/a.txt", "/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := lfs.links["/b.txt"]; got != "/a.txt" {
+		t.Errorf("link target = %q, want /a.txt", got)
+	}
+	if err := httpfs.New(lfs).Link("/missing.txt", "/c.txt"); !os.IsNotExist(err) {
+		t.Errorf("Link to a missing file = %v", err)
+	}
+	if err := httpfs.New(lfs, httpfs.WithReadOnly(true)).Link("/a.txt", "/d.txt"); !os.IsPermission(err) {
+		t.Errorf("Link on a read-only filesystem = %v", err)
+	}
+
+	restricted := httpfs.New(lfs,
+		httpfs.WithDenyGlobs("*.key"),
+		httpfs.WithAllowedExtensions(".txt", ".key"),
+		httpfs.WithHideDotfiles(true))
+	for name, want := range map[string]error{
+		"/secret.key": fs.ErrPermission,
+		"/tool.exe":   fs.ErrPermission,
+		"/.env.txt":   fs.ErrNotExist,
+	} {
+		if err := restricted.Link("/a.txt", name); !errors.Is(err, want) {
+			t.Errorf("Link to %s = %v, want %v", name, err, want)
+		}
+		if _, ok := lfs.links[name]; ok {
+			t.Errorf("Link to %s created the link", name)
+		}
+	}
+
+	err := httpfs.New(newMemFS(t, map[string]string{"/a.txt": "a"})).Link("/a.txt", "/b.txt")
+	var lerr *os.LinkError
+	if !errors.Is(err, httpfs.ErrNotSupported) || !errors.As(err, &lerr) {
+		t.Errorf("Link without support = %v, want a *os.LinkError wrapping ErrNotSupported", err)
+	}
+}
+
+// followFS is a filer over the host directory root that follows symbolic
+// links without reporting them, having no Lstat.
+type followFS struct {
+	root string
+}
+
+func (fs *followFS) path(name string) string {
+	return filepath.Join(fs.root, filepath.FromSlash(name))
+}
+
+func (fs *followFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	return os.OpenFile(fs.path(name), flag, perm)
+}
+
+func (fs *followFS) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(fs.path(name), perm)
+}
+
+func (fs *followFS) Remove(name string) error {
+	return os.Remove(fs.path(name))
+}
+
+func (fs *followFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(fs.path(name))
+}
+
+func (fs *followFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(fs.path(name), mode)
+}
+
+func (fs *followFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(fs.path(name), atime, mtime)
+}
+
+func (fs *followFS) Chown(name string, uid, gid int) error {
+	return os.Chown(fs.path(name), uid, gid)
+}
+
+func TestRemoveAllSymlinkCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "tree", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "tree", "sub", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(root, "tree", "sub", "loop")); err != nil {
+		t.Skip(err)
+	}
+
+	filer := httpfs.New(&followFS{root: root})
+	if info, err := filer.Stat("/tree/sub/loop/sub/loop"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat through the cycle = %v, %v; want a directory", info, err)
+	}
+	if err := filer.RemoveAll("/tree"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "tree")); !os.IsNotExist(err) {
+		t.Errorf("tree still exists: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package httpfs
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/absfs/absfs"
+)
+
+// WithStrictSync makes Sync fail with ErrNotSupported on filers whose files
+// cannot be synced, instead of treating their files as already durable.
+func WithStrictSync(strict bool) Option {
+	return func(filer *Httpfs) {
+		filer.strictSync = strict
+	}
+}
+
+// WithSyncOnClose makes the files OpenFile opens for writing sync their
+// contents to stable storage when closed, so that Close reports the errors
+// of filers that buffer writes. Close returns the first error of Sync and of
+// closing the file, ignoring Sync errors from filers whose files cannot be
+// synced unless WithStrictSync is set, and closing a file again is a no-op
+// returning nil.
+func WithSyncOnClose(enabled bool) Option {
+	return func(filer *Httpfs) {
+		filer.syncOnClose = enabled
+	}
+}
+
+// Sync commits the contents of the file name to stable storage, for filers
+// that buffer writes, by calling Sync on the open file. If the filer's files
+// do not support Sync it returns nil, or fails with ErrNotSupported if
+// WithStrictSync is set.
+func (filer *Httpfs) Sync(name string) error {
+	if err := filer.acquire(); err != nil {
+		return &os.PathError{Op: "sync", Path: name, Err: err}
+	}
+	defer filer.release()
+	if filer.hidden(name) {
+		return &os.PathError{Op: "sync", Path: name, Err: fs.ErrNotExist}
+	}
+	p, err := filer.resolve("sync", name)
+	if err != nil {
+		return err
+	}
+	defer filer.lock(p, false)()
+	f, err := filer.fs.OpenFile(p, os.O_RDONLY, 0)
+	if err != nil {
+		return pathError("sync", name, err)
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if unsupported(err) {
+		if !filer.strictSync {
+			return nil
+		}
+		err = ErrNotSupported
+	}
+	return pathError("sync", name, err)
+}
+
+// syncingFile is a file opened for writing with WithSyncOnClose set.
+type syncingFile struct {
+	absfs.File
+	name   string
+	strict bool
+	closed bool
+}
+
+func (f *syncingFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	err := f.File.Sync()
+	if unsupported(err) && !f.strict {
+		err = nil
+	}
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	return pathError("close", f.name, err)
+}
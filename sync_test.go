@@ -0,0 +1,118 @@
+package httpfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// syncFS records the names of the files synced and closed, failing with err
+// and closeErr.
+type syncFS struct {
+	absfs.Filer
+	err, closeErr error
+	synced        []string
+	closed        int
+}
+
+func (fs *syncFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &syncFile{File: f, fs: fs, name: name}, nil
+}
+
+type syncFile struct {
+	absfs.File
+	fs   *syncFS
+	name string
+}
+
+func (f *syncFile) Sync() error {
+	f.fs.synced = append(f.fs.synced, f.name)
+	return f.fs.err
+}
+
+func (f *syncFile) Close() error {
+	f.fs.closed++
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return f.fs.closeErr
+}
+
+func TestSync(t *testing.T) {
+	sfs := &syncFS{Filer: newMemFS(t, map[string]string{"/upload.bin": "data"})}
+	fs := httpfs.New(sfs)
+
+	if err := fs.Sync("/upload.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sfs.synced) != 1 || sfs.synced[0] != "/upload.bin" {
+		t.Errorf("synced %q", sfs.synced)
+	}
+	if err := fs.Sync("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Sync(/missing) err = %v", err)
+	}
+
+	sfs.err = errors.ErrUnsupported
+	if err := fs.Sync("/upload.bin"); err != nil {
+		t.Errorf("unsupported Sync err = %v, want nil", err)
+	}
+	strict := httpfs.New(sfs, httpfs.WithStrictSync(true))
+	if err := strict.Sync("/upload.bin"); !errors.Is(err, httpfs.ErrNotSupported) {
+		t.Errorf("strict unsupported Sync err = %v", err)
+	}
+
+	sfs.err = errors.New("disk on fire")
+	if err := fs.Sync("/upload.bin"); err == nil {
+		t.Error("Sync error lost")
+	}
+}
+
+func TestSyncOnClose(t *testing.T) {
+	sfs := &syncFS{Filer: newMemFS(t, nil)}
+	fs := httpfs.New(sfs, httpfs.WithSyncOnClose(true))
+
+	f, err := fs.Create("/upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("data"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sfs.synced) != 1 || sfs.closed != 1 {
+		t.Errorf("synced %q, closed %d times", sfs.synced, sfs.closed)
+	}
+
+	sfs.closeErr = errors.New("write back failed")
+	f, err = fs.Create("/upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); !errors.Is(err, sfs.closeErr) {
+		t.Errorf("Close err = %v, want %v", err, sfs.closeErr)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("second Close err = %v, want nil", err)
+	}
+	if sfs.closed != 2 {
+		t.Errorf("closed %d times, want 2", sfs.closed)
+	}
+
+	// files opened for reading are not synced
+	sfs.closeErr = nil
+	rf, err := fs.Open("/upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.Close()
+	if len(sfs.synced) != 2 {
+		t.Errorf("synced %q", sfs.synced)
+	}
+}
@@ -0,0 +1,56 @@
+package httpfs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// timesSetter is implemented by files whose times can be set through the
+// open file.
+type timesSetter interface {
+	SetTimes(atime, mtime time.Time) error
+}
+
+// unsupported reports whether err, returned by the underlying filer, means
+// that it does not support the operation.
+func unsupported(err error) bool {
+	return errors.Is(err, ErrNotSupported) || errors.Is(err, ErrNotImplemented) ||
+		errors.Is(err, errors.ErrUnsupported) || errors.Is(err, syscall.ENOSYS)
+}
+
+// setTimes sets the times of the file at p in the underlying filer through
+// the open file, for filers whose Chtimes is not supported. It fails with
+// ErrNotSupported if the file cannot set its times either.
+func (filer *Httpfs) setTimes(p string, atime, mtime time.Time) error {
+	f, err := filer.fs.OpenFile(p, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ts, ok := f.(timesSetter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return ts.SetTimes(atime, mtime)
+}
+
+// Touch sets the access and modification times of the file name to the
+// current time, as the touch command does, creating it empty if it does not
+// exist.
+func (filer *Httpfs) Touch(name string) error {
+	_, err := filer.Stat(name)
+	if os.IsNotExist(err) {
+		f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return filer.Chtimes(name, now, now)
+}
@@ -0,0 +1,108 @@
+package httpfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+// noChtimesFS is a filer without Chtimes whose files can set their times if
+// setTimes is set.
+type noChtimesFS struct {
+	absfs.Filer
+	setTimes bool
+	mtimes   map[string]time.Time
+}
+
+func (fs *noChtimesFS) Chtimes(name string, atime, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: httpfs.ErrNotSupported}
+}
+
+func (fs *noChtimesFS) OpenFile(name string, flag int, perm os.FileMode) (absfs.File, error) {
+	f, err := fs.Filer.OpenFile(name, flag, perm)
+	if err != nil || !fs.setTimes {
+		return f, err
+	}
+	return &timesFile{File: f, fs: fs, name: name}, nil
+}
+
+type timesFile struct {
+	absfs.File
+	fs   *noChtimesFS
+	name string
+}
+
+func (f *timesFile) SetTimes(atime, mtime time.Time) error {
+	f.fs.mtimes[f.name] = mtime
+	return nil
+}
+
+func TestChtimesFallback(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	withSetTimes := &noChtimesFS{Filer: newMemFS(t, map[string]string{"/a.txt": "a"}), setTimes: true, mtimes: map[string]time.Time{}}
+	if err := httpfs.New(withSetTimes).Chtimes("/a.txt", mtime, mtime); err != nil {
+		t.Errorf("Chtimes through the file: %v", err)
+	}
+	if got := withSetTimes.mtimes["/a.txt"]; !got.Equal(mtime) {
+		t.Errorf("mtime set through the file = %v, want %v", got, mtime)
+	}
+
+	without := &noChtimesFS{Filer: newMemFS(t, map[string]string{"/a.txt": "a"})}
+	err := httpfs.New(without).Chtimes("/a.txt", mtime, mtime)
+	var perr *os.PathError
+	if !errors.Is(err, httpfs.ErrNotSupported) || !errors.As(err, &perr) {
+		t.Errorf("Chtimes without support = %v, want a *os.PathError wrapping ErrNotSupported", err)
+	}
+
+	fs := httpfs.New(newMemFS(t, map[string]string{"/a.txt": "a"}))
+	if err := fs.Chtimes("/a.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := fs.Stat("/a.txt"); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("Stat after Chtimes = %v, %v", info, err)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	mfs := newMemFS(t, map[string]string{"/dir/old.txt": "contents"})
+	past := time.Now().Add(-time.Hour)
+	if err := mfs.Chtimes("/dir/old.txt", past, past); err != nil {
+		t.Fatal(err)
+	}
+	fs := httpfs.New(mfs)
+
+	if err := fs.Touch("/dir/old.txt"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat("/dir/old.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(past.Add(time.Minute)) {
+		t.Errorf("modtime = %v, not advanced from %v", info.ModTime(), past)
+	}
+	if got := readFile(t, fs, "/dir/old.txt"); got != "contents" {
+		t.Errorf("contents = %q after Touch", got)
+	}
+
+	before := time.Now().Add(-time.Second)
+	if err := fs.Touch("/dir/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	info, err = fs.Stat("/dir/new.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 || info.IsDir() || info.ModTime().Before(before) {
+		t.Errorf("new file: size %d, modtime %v", info.Size(), info.ModTime())
+	}
+
+	if err := fs.Touch("/missing/new.txt"); !os.IsNotExist(err) {
+		t.Errorf("Touch in a missing directory: err = %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package httpfs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// writeFlags are the OpenFile flags that create or modify a file.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+// isWrite reports whether flag opens a file for writing.
+func isWrite(flag int) bool {
+	return flag&writeFlags != 0
+}
+
+// WithDenyGlobs refuses writes to files matching any of patterns. Patterns
+// use path.Match syntax and are matched against both the cleaned path and its
+// base name, so "*.exe" denies executables anywhere while "/private/*" denies
+// the contents of a single directory.
+func WithDenyGlobs(patterns ...string) Option {
+	return func(filer *Httpfs) {
+		filer.denyGlobs = append(filer.denyGlobs, patterns...)
+	}
+}
+
+// WithAllowedExtensions restricts writes to files whose extension, compared
+// case insensitively, is one of exts, such as ".txt".
+func WithAllowedExtensions(exts ...string) Option {
+	return func(filer *Httpfs) {
+		for _, ext := range exts {
+			filer.allowedExts = append(filer.allowedExts, strings.ToLower(ext))
+		}
+	}
+}
+
+// CheckUploadPath runs the checks an upload to name would, returning the
+// error the upload would fail with, or nil. Besides the checks of writes,
+// dotfiles hidden by WithHideDotfiles and names an overlay reserves for
+// whiteouts are refused, and ErrQuotaExceeded is returned if the quota set
+// with WithQuota leaves no room for a single byte once any file name holds
+// is replaced. Nothing is created.
+func (filer *Httpfs) CheckUploadPath(name string) error {
+	if filer.hidden(name) {
+		return &os.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := filer.checkWrite(name); err != nil {
+		return err
+	}
+	p, err := filer.resolve("open", name)
+	if err != nil {
+		return err
+	}
+	if _, ok := filer.fs.(*overlay); ok {
+		if err := reserved("open", p); err != nil {
+			return err
+		}
+	}
+	if filer.quota != nil && filer.quota.full(p) {
+		return &os.PathError{Op: "write", Path: name, Err: ErrQuotaExceeded}
+	}
+	return nil
+}
+
+// checkWrite returns an error if the file name may not be written, including
+// when it exists as a directory.
+func (filer *Httpfs) checkWrite(name string) error {
+	if err := filer.checkReadOnly("open", name); err != nil {
+		return err
+	}
+	clean, err := filer.checkDenied(name)
+	if err != nil {
+		return err
+	}
+
+	if len(filer.allowedExts) > 0 && !filer.allowedExt(clean) {
+		return &os.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	p, err := filer.resolve("open", name)
+	if err != nil {
+		return err
+	}
+	if info, err := filer.fs.Stat(p); err == nil && info.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+	return nil
+}
+
+// checkDenied returns the cleaned name, or an error if name is invalid or
+// matches a deny glob.
+func (filer *Httpfs) checkDenied(name string) (string, error) {
+	clean := path.Clean("/" + filer.slashPath(name))
+	if clean == "/" || strings.ContainsRune(name, 0) {
+		return "", &os.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	full := path.Join(filer.globBase, clean)
+	for _, pattern := range filer.denyGlobs {
+		if match(pattern, full) || match(pattern, path.Base(clean)) {
+			return "", &os.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+	}
+	return clean, nil
+}
+
+// allowedExt reports whether the extension of name is allowed.
+func (filer *Httpfs) allowedExt(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range filer.allowedExts {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, name string) bool {
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// WithMaxUploadSize limits the bodies of PUT requests, and of requests to
+// UploadHandler, to n bytes. Larger bodies are answered with 413 Request
+// Entity Too Large, and the file being written when the limit is reached is
+// removed.
+func WithMaxUploadSize(n int64) Option {
+	return func(filer *Httpfs) {
+		filer.maxUploadSize = n
+	}
+}
+
+// limitBody limits the body of r to the maximum upload size, if set. It
+// answers r with 413 Request Entity Too Large and returns false if the
+// declared length of the body is over the limit.
+func (filer *Httpfs) limitBody(w http.ResponseWriter, r *http.Request) bool {
+	if filer.maxUploadSize <= 0 {
+		return true
+	}
+	if r.ContentLength > filer.maxUploadSize {
+		filer.serveStatus(w, r, http.StatusRequestEntityTooLarge, nil)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, filer.maxUploadSize)
+	return true
+}
+
+// tooLarge reports whether err is from reading a body over the maximum upload
+// size.
+func tooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// servePut writes the body of r to the file named by the request path.
+func (filer *Httpfs) servePut(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	if !filer.limitBody(w, r) {
+		return
+	}
+	if key := r.Header.Get("Idempotency-Key"); key != "" && filer.idempotency != nil {
+		err := filer.idempotency.serve(w, r, key, name, func(body io.Reader) (int, error) {
+			return filer.writeUpload(name, body)
+		})
+		if err != nil {
+			filer.serveError(w, r, err)
+		}
+		return
+	}
+
+	status, err := filer.writeUpload(name, r.Body)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// writeUpload writes body to the file name. It returns 201 Created if the
+// file is new and 204 No Content if it was replaced.
+func (filer *Httpfs) writeUpload(name string, body io.Reader) (int, error) {
+	status := http.StatusNoContent
+	if _, err := filer.Stat(name); os.IsNotExist(err) {
+		status = http.StatusCreated
+	}
+
+	f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if tooLarge(err) {
+		filer.Remove(name)
+	}
+	return status, err
+}
+
+// uploadedFile is a file stored by UploadHandler.
+type uploadedFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// UploadHandler returns a handler storing the files posted to it as
+// multipart/form-data in the directory destDir, which is created if missing.
+// Parts are streamed to the filesystem as they are read, each under the base
+// name of its filename; parts without a filename are ignored. The handler
+// answers with 201 Created and a JSON array describing the stored files.
+// Methods other than POST are answered with 405 Method Not Allowed, bodies
+// that are not multipart or name a file "." or ".." with 400 Bad Request,
+// and errors writing a file as ServeHTTP answers them.
+func (filer *Httpfs) UploadHandler(destDir string) http.Handler {
+	destDir = path.Clean("/" + destDir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !filer.limitBody(w, r) {
+			return
+		}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err := filer.MkdirAll(destDir, 0755); err != nil {
+			filer.serveError(w, r, err)
+			return
+		}
+
+		files := []uploadedFile{}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if tooLarge(err) {
+				filer.serveError(w, r, err)
+				return
+			}
+			if err != nil {
+				http.Error(w, "400 Bad Request", http.StatusBadRequest)
+				return
+			}
+			if part.FileName() == "" {
+				part.Close()
+				continue
+			}
+			base, ok := uploadName(part.FileName())
+			if !ok {
+				part.Close()
+				http.Error(w, "400 Bad Request", http.StatusBadRequest)
+				return
+			}
+			name := path.Join(destDir, base)
+			n, err := filer.writePart(name, part)
+			part.Close()
+			if err != nil {
+				filer.serveError(w, r, err)
+				return
+			}
+			files = append(files, uploadedFile{Name: base, Path: name, Size: n})
+		}
+
+		data, err := json.Marshal(files)
+		if err != nil {
+			filer.serveError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	})
+}
+
+// uploadName returns the base name of the filename a client sent with a
+// part, which may be a full path using either slash, and reports whether it
+// is usable.
+func uploadName(filename string) (string, bool) {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if base == "." || base == ".." || base == "/" || strings.ContainsRune(base, 0) {
+		return "", false
+	}
+	return base, true
+}
+
+// writePart writes r to the file name, returning the number of bytes written.
+func (filer *Httpfs) writePart(name string, r io.Reader) (int64, error) {
+	f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if tooLarge(err) {
+		filer.Remove(name)
+	}
+	return n, err
+}
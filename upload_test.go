@@ -0,0 +1,221 @@
+package httpfs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestCheckUploadPath(t *testing.T) {
+	filer := httpfs.New(newMemFS(t, map[string]string{"/private/.keep": ""}),
+		httpfs.WithDenyGlobs("*.secret", "/private/*"),
+		httpfs.WithAllowedExtensions(".txt", ".PNG"))
+
+	for _, name := range []string{"/tool.exe", "/private/notes.txt", "/dir/key.secret", "/"} {
+		checkErr := filer.CheckUploadPath(name)
+		if checkErr == nil {
+			t.Errorf("%s: CheckUploadPath accepted a rejected path", name)
+			continue
+		}
+		f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err == nil {
+			f.Close()
+			t.Errorf("%s: upload accepted", name)
+			continue
+		}
+		if err.Error() != checkErr.Error() {
+			t.Errorf("%s: CheckUploadPath err = %v, upload err = %v", name, checkErr, err)
+		}
+	}
+	if err := filer.CheckUploadPath("/tool.exe"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("denied extension: err = %v, want %v", err, fs.ErrPermission)
+	}
+
+	for _, name := range []string{"/notes.txt", "/img/logo.png"} {
+		if err := filer.CheckUploadPath(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if _, err := filer.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s: CheckUploadPath created the file", name)
+		}
+	}
+
+	// reads are unaffected
+	f, err := filer.OpenFile("/private/.keep", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestCheckUploadPathReserved(t *testing.T) {
+	upload := func(filer *httpfs.Httpfs, name string) error {
+		f, err := filer.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte("x"))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	mirror := func(filer *httpfs.Httpfs, name string, want error) {
+		t.Helper()
+		checkErr := filer.CheckUploadPath(name)
+		if !errors.Is(checkErr, want) {
+			t.Errorf("%s: CheckUploadPath err = %v, want %v", name, checkErr, want)
+			return
+		}
+		if err := upload(filer, name); err == nil || err.Error() != checkErr.Error() {
+			t.Errorf("%s: CheckUploadPath err = %v, upload err = %v", name, checkErr, err)
+		}
+	}
+
+	hiding := httpfs.New(newMemFS(t, nil), httpfs.WithHideDotfiles(true))
+	mirror(hiding, "/.env", fs.ErrNotExist)
+	mirror(hiding, "/.git/config", fs.ErrNotExist)
+
+	overlay := httpfs.NewOverlay(newMemFS(t, nil), newMemFS(t, nil))
+	mirror(overlay, "/.wh.notes.txt", syscall.EINVAL)
+	if err := overlay.CheckUploadPath("/notes.txt"); err != nil {
+		t.Errorf("overlay: %v", err)
+	}
+
+	quota := httpfs.New(newMemFS(t, nil), httpfs.WithQuota(4))
+	writeFile(t, quota, "/a.txt", "aaaa")
+	mirror(quota, "/b.txt", httpfs.ErrQuotaExceeded)
+	// Replacing a file frees the bytes charged for it first.
+	if err := quota.CheckUploadPath("/a.txt"); err != nil {
+		t.Errorf("replacing a file within the quota: %v", err)
+	}
+	if err := upload(quota, "/a.txt"); err != nil {
+		t.Errorf("replacing a file within the quota: %v", err)
+	}
+	if err := quota.CheckUploadPath("/b.txt"); err != nil {
+		t.Errorf("quota headroom after replacing a file: %v", err)
+	}
+}
+
+func TestWriteTypeMismatch(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{"/dir/file.txt": "x"}))
+
+	_, err := fs.OpenFile("/dir", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("write to directory: err = %v, want EISDIR", err)
+	}
+	if err := fs.CheckUploadPath("/dir"); !errors.Is(err, syscall.EISDIR) {
+		t.Errorf("CheckUploadPath on directory: err = %v, want EISDIR", err)
+	}
+
+	err = fs.Mkdir("/dir/file.txt", 0755)
+	if !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("Mkdir over file: err = %v, want ENOTDIR", err)
+	}
+	err = fs.MkdirAll("/dir/file.txt/sub", 0755)
+	if !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("MkdirAll through file: err = %v, want ENOTDIR", err)
+	}
+	if err := fs.Mkdir("/dir", 0755); !os.IsExist(err) {
+		t.Errorf("Mkdir over directory: err = %v, want exist", err)
+	}
+}
+
+func TestUploadHandler(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, nil))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("comment", "not a file")
+	for name, data := range map[string]string{`C:\photos\a.txt`: "alpha", "../../b.txt": "beta"} {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(data))
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	fs.UploadHandler("/uploads").ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var stored []struct {
+		Name, Path string
+		Size       int64
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("stored %+v, want 2 files", stored)
+	}
+	for name, want := range map[string]string{"/uploads/a.txt": "alpha", "/uploads/b.txt": "beta"} {
+		if got := readFile(t, fs, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	fs.UploadHandler("/uploads").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("non-multipart status = %d, want 400", rec.Code)
+	}
+}
+
+func TestMaxUploadSize(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, nil), httpfs.WithMaxUploadSize(8))
+
+	put := func(name string, body io.Reader) int {
+		req := httptest.NewRequest(http.MethodPut, name, body)
+		rec := httptest.NewRecorder()
+		fs.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := put("/small.txt", strings.NewReader("12345678")); code != http.StatusCreated {
+		t.Errorf("PUT at the limit: status = %d", code)
+	}
+	if code := put("/declared.txt", strings.NewReader("123456789")); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("PUT with a large Content-Length: status = %d", code)
+	}
+	// a body of unknown length is cut off while it is written
+	if code := put("/streamed.txt", io.MultiReader(strings.NewReader("12345"), strings.NewReader("6789"))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed PUT: status = %d", code)
+	}
+	for _, name := range []string{"/declared.txt", "/streamed.txt"} {
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", name, err)
+		}
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "big.bin")
+	fw.Write(bytes.Repeat([]byte("x"), 64))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(&body))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	fs.UploadHandler("/uploads").ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("multipart upload: status = %d", rec.Code)
+	}
+	if _, err := fs.Stat("/uploads/big.bin"); !os.IsNotExist(err) {
+		t.Errorf("partial upload left behind: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package httpfs
+
+import "os"
+
+// WithDirectorySizes makes DiskUsage count the sizes the underlying filer
+// reports for directories. By default only regular files are counted.
+func WithDirectorySizes(count bool) Option {
+	return func(filer *Httpfs) {
+		filer.dirSizes = count
+	}
+}
+
+// DiskUsage returns the total size of the regular files in the tree rooted at
+// root, or of root itself if it is a file. Symbolic links and other special
+// files count for nothing, and so do directories unless WithDirectorySizes is
+// set. It fails on the first error reading the tree.
+func (filer *Httpfs) DiskUsage(root string) (int64, error) {
+	var total int64
+	err := filer.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() || info.IsDir() && filer.dirSizes {
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,41 @@
+package httpfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestDiskUsage(t *testing.T) {
+	files := map[string]string{
+		"/site/index.html":     "<h1>hi</h1>",
+		"/site/blog/post.html": "a post",
+		"/site/blog/empty.txt": "",
+		"/other.txt":           "not counted",
+	}
+	fs := httpfs.New(newMemFS(t, files))
+
+	want := int64(len(files["/site/index.html"]) + len(files["/site/blog/post.html"]))
+	if n, err := fs.DiskUsage("/site"); err != nil || n != want {
+		t.Errorf("DiskUsage(/site) = %d, %v, want %d", n, err, want)
+	}
+	if n, err := fs.DiskUsage("/site/blog/post.html"); err != nil || n != 6 {
+		t.Errorf("DiskUsage(post.html) = %d, %v, want 6", n, err)
+	}
+	if _, err := fs.DiskUsage("/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DiskUsage(/missing) error = %v", err)
+	}
+
+	// Directory sizes are whatever the filer reports, so only check that
+	// counting them adds to the total.
+	dirs := httpfs.New(newMemFS(t, files), httpfs.WithDirectorySizes(true))
+	info, err := dirs.Stat("/site/blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := dirs.DiskUsage("/site/blog"); err != nil || n != 6+info.Size() {
+		t.Errorf("DiskUsage(/site/blog) with directory sizes = %d, %v, want %d", n, err, 6+info.Size())
+	}
+}
@@ -0,0 +1,77 @@
+package httpfs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// Walk walks the file tree rooted at root as filepath.Walk does, calling fn
+// for each file or directory in the tree, including root, in lexical order.
+// Errors reading root or a directory are passed to fn, which decides whether
+// the walk goes on. fn may return filepath.SkipDir to skip a directory, or
+// filepath.SkipAll to stop the walk.
+func (filer *Httpfs) Walk(root string, fn filepath.WalkFunc) error {
+	info, err := filer.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = filer.walk(root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walk recursively descends name, calling fn.
+func (filer *Httpfs) walk(name string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(name, info, nil)
+	}
+
+	infos, err := filer.listDir(name)
+	err1 := fn(name, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, info := range infos {
+		err = filer.walk(path.Join(name, info.Name()), info, fn)
+		if err != nil && (!info.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDirDeep returns the cleaned absolute paths of all files and directories
+// under root, not including root, in sorted order. Symbolic links to
+// directories are listed but not followed, so links forming a cycle cannot
+// make it loop.
+func (filer *Httpfs) ReadDirDeep(root string) ([]string, error) {
+	root = path.Clean("/" + root)
+	var names []string
+	err := filer.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == root {
+			return nil
+		}
+		names = append(names, name)
+		if info.IsDir() {
+			// Readdir may report the target of a link rather than the link.
+			if linfo, err := filer.lstat(name); err == nil && linfo.Mode()&os.ModeSymlink != 0 {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,128 @@
+package httpfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/absfs/httpfs"
+)
+
+func TestWalk(t *testing.T) {
+	files := map[string]string{
+		"/site/b.html":         "",
+		"/site/a.html":         "",
+		"/site/blog/post.html": "",
+		"/site/skip/x.html":    "",
+		"/site/z/deep/y.html":  "",
+	}
+	fs := httpfs.New(newMemFS(t, files))
+
+	var visited []string
+	err := fs.Walk("/site", func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, name)
+		if info.IsDir() && info.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/site",
+		"/site/a.html",
+		"/site/b.html",
+		"/site/blog",
+		"/site/blog/post.html",
+		"/site/skip",
+		"/site/z",
+		"/site/z/deep",
+		"/site/z/deep/y.html",
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %q, want %q", visited, want)
+	}
+
+	visited = nil
+	err = fs.Walk("/site", func(name string, info os.FileInfo, err error) error {
+		visited = append(visited, name)
+		if name == "/site/b.html" {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil || len(visited) != 3 {
+		t.Errorf("SkipAll: visited %q, err %v", visited, err)
+	}
+
+	var gotErr error
+	err = fs.Walk("/missing", func(name string, info os.FileInfo, err error) error {
+		gotErr = err
+		return err
+	})
+	if !os.IsNotExist(gotErr) || !os.IsNotExist(err) {
+		t.Errorf("missing root: fn got %v, Walk returned %v", gotErr, err)
+	}
+}
+
+func TestWalkReadDirErrors(t *testing.T) {
+	fs := httpfs.New(&partialReaddirFS{newMemFS(t, map[string]string{"/dir/a.txt": "a"})})
+
+	var errs []error
+	err := fs.Walk("/dir", func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errDirChanged) {
+		t.Errorf("fn got errors %v, want %v", errs, errDirChanged)
+	}
+
+	err = fs.Walk("/dir", func(name string, info os.FileInfo, err error) error {
+		return err
+	})
+	if !errors.Is(err, errDirChanged) {
+		t.Errorf("Walk returned %v, want %v", err, errDirChanged)
+	}
+}
+
+func TestReadDirDeep(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/site/b.html":         "",
+		"/site/a.html":         "",
+		"/site/blog/post.html": "",
+		"/site/z/deep/y.html":  "",
+		"/other.html":          "",
+	}))
+
+	names, err := fs.ReadDirDeep("site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/site/a.html",
+		"/site/b.html",
+		"/site/blog",
+		"/site/blog/post.html",
+		"/site/z",
+		"/site/z/deep",
+		"/site/z/deep/y.html",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDirDeep = %q, want %q", names, want)
+	}
+
+	if _, err := fs.ReadDirDeep("/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadDirDeep(/missing) error = %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package httpfs
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// WebDAVHandler returns a handler serving the filesystem as a minimal WebDAV
+// server, without locking, so that clients can mount it. PROPFIND requests
+// with a Depth of 0 or 1 are answered with the resource type, size and
+// modtime of the named file or directory and, at depth 1, of its entries;
+// a missing Depth is taken as 1 and infinite depth is refused. MOVE requests
+// rename the file or directory to the path of the Destination header,
+// honoring the Overwrite header. GET, HEAD, PUT, DELETE and MKCOL requests are
+// handled as ServeHTTP handles them. The handler must be mounted at the root
+// of the URL space, since request paths are used as file names and hrefs.
+func (filer *Httpfs) WebDAVHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, "MKCOL":
+			filer.ServeHTTP(w, r)
+		case "PROPFIND":
+			filer.servePropfind(w, r)
+		case "MOVE":
+			filer.serveMove(w, r)
+		case http.MethodOptions:
+			w.Header().Set("DAV", "1")
+			w.Header().Set("Allow", webDAVMethods)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Allow", webDAVMethods)
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// webDAVMethods are the methods allowed by WebDAVHandler.
+const webDAVMethods = "OPTIONS, GET, HEAD, PUT, DELETE, MKCOL, PROPFIND, MOVE"
+
+// davMultistatus is the body of a PROPFIND response.
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"href"`
+	Propstat davPropstat `xml:"propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	DisplayName   string          `xml:"displayname"`
+	ResourceType  davResourceType `xml:"resourcetype"`
+	ContentLength string          `xml:"getcontentlength,omitempty"`
+	LastModified  string          `xml:"getlastmodified"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"collection"`
+}
+
+// servePropfind answers a PROPFIND request for the file or directory named by
+// the request path, listing the entries of directories at depth 1. The
+// requested properties are ignored and all of them are returned.
+func (filer *Httpfs) servePropfind(w http.ResponseWriter, r *http.Request) {
+	depth := r.Header.Get("Depth")
+	if depth != "" && depth != "0" && depth != "1" {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	info, err := filer.Stat(name)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+
+	ms := davMultistatus{Responses: []davResponse{davEntry(name, info)}}
+	if info.IsDir() && depth != "0" {
+		if !filer.mayList(r, name) {
+			filer.serveStatus(w, r, http.StatusForbidden, nil)
+			return
+		}
+		infos, err := filer.listDir(name)
+		if err != nil {
+			filer.serveError(w, r, err)
+			return
+		}
+		for _, info := range infos {
+			ms.Responses = append(ms.Responses, davEntry(path.Join(name, info.Name()), info))
+		}
+	}
+
+	data, err := xml.Marshal(ms)
+	if err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// davEntry returns the PROPFIND response for the file name whose FileInfo is
+// info.
+func davEntry(name string, info os.FileInfo) davResponse {
+	prop := davProp{
+		DisplayName:  info.Name(),
+		LastModified: info.ModTime().UTC().Format(http.TimeFormat),
+	}
+	href := (&url.URL{Path: name}).EscapedPath()
+	if info.IsDir() {
+		prop.ResourceType.Collection = &struct{}{}
+		href = dirPath(href)
+	} else {
+		prop.ContentLength = strconv.FormatInt(info.Size(), 10)
+	}
+	return davResponse{
+		Href:     href,
+		Propstat: davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"},
+	}
+}
+
+// serveMove renames the file or directory named by the request path to the
+// path of the Destination header. An existing destination is replaced unless
+// the Overwrite header is F. It is kept if the move fails, unless copying
+// the files fails on a filer that cannot rename them. Moves onto an ancestor
+// of the source or into the source itself are refused with 403 Forbidden.
+func (filer *Httpfs) serveMove(w http.ResponseWriter, r *http.Request) {
+	u, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil || u.Path == "" {
+		http.Error(w, "400 Bad Request", http.StatusBadRequest)
+		return
+	}
+	if u.Host != "" && u.Host != r.Host {
+		http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	src, dst := path.Clean("/"+r.URL.Path), path.Clean("/"+u.Path)
+	if src == "/" || dst == "/" || src == dst || nested(src, dst) || nested(dst, src) {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	if _, err := filer.Stat(src); err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	if _, err := filer.Stat(path.Dir(dst)); os.IsNotExist(err) {
+		http.Error(w, "409 Conflict", http.StatusConflict)
+		return
+	}
+
+	status := http.StatusCreated
+	rename := filer.Rename
+	if _, err := filer.Stat(dst); err == nil {
+		if r.Header.Get("Overwrite") == "F" {
+			http.Error(w, "412 Precondition Failed", http.StatusPreconditionFailed)
+			return
+		}
+		status, rename = http.StatusNoContent, filer.renameOver
+	}
+	if err := rename(src, dst); err != nil {
+		filer.serveError(w, r, err)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// nested reports whether the cleaned path name lies inside the directory dir.
+func nested(name, dir string) bool {
+	return strings.HasPrefix(name, dir+"/")
+}
@@ -0,0 +1,153 @@
+package httpfs_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/absfs/absfs"
+	"github.com/absfs/httpfs"
+)
+
+func propfind(t *testing.T, h http.Handler, target, depth string) []string {
+	t.Helper()
+	r := httptest.NewRequest("PROPFIND", target, nil)
+	r.Header.Set("Depth", depth)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND %s: status %d", target, w.Code)
+	}
+
+	var ms struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &ms); err != nil {
+		t.Fatalf("PROPFIND %s: %v\n%s", target, err, w.Body)
+	}
+	var hrefs []string
+	for _, resp := range ms.Responses {
+		hrefs = append(hrefs, resp.Href)
+	}
+	return hrefs
+}
+
+func TestWebDAVPropfind(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/docs/a.txt":        "a",
+		"/docs/my notes.txt": "notes",
+		"/docs/sub/deep.txt": "deep",
+	}))
+	h := fs.WebDAVHandler()
+
+	want := []string{"/docs/", "/docs/a.txt", "/docs/my%20notes.txt", "/docs/sub/"}
+	if got := propfind(t, h, "/docs", "1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("depth 1 hrefs = %q, want %q", got, want)
+	}
+	if got := propfind(t, h, "/docs", "0"); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("depth 0 hrefs = %q, want %q", got, want[:1])
+	}
+
+	r := httptest.NewRequest("PROPFIND", "/docs", nil)
+	r.Header.Set("Depth", "infinity")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("infinite depth: status %d", w.Code)
+	}
+}
+
+func TestWebDAVMove(t *testing.T) {
+	fs := httpfs.New(newMemFS(t, map[string]string{
+		"/a.txt":     "a",
+		"/b.txt":     "b",
+		"/dir/c.txt": "c",
+	}))
+	h := fs.WebDAVHandler()
+
+	move := func(src, dst, overwrite string) int {
+		r := httptest.NewRequest("MOVE", src, nil)
+		r.Header.Set("Destination", "http://example.com"+dst)
+		if overwrite != "" {
+			r.Header.Set("Overwrite", overwrite)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := move("/a.txt", "/dir/a.txt", ""); code != http.StatusCreated {
+		t.Errorf("move to a new path: status %d", code)
+	}
+	if got := readFile(t, fs, "/dir/a.txt"); got != "a" {
+		t.Errorf("moved file = %q", got)
+	}
+	if code := move("/b.txt", "/dir/c.txt", "F"); code != http.StatusPreconditionFailed {
+		t.Errorf("move over a file without overwrite: status %d", code)
+	}
+	if code := move("/b.txt", "/dir/c.txt", ""); code != http.StatusNoContent {
+		t.Errorf("move over a file: status %d", code)
+	}
+	if got := readFile(t, fs, "/dir/c.txt"); got != "b" {
+		t.Errorf("replaced file = %q", got)
+	}
+	if code := move("/dir", "/missing/dir", ""); code != http.StatusConflict {
+		t.Errorf("move into a missing directory: status %d", code)
+	}
+
+	if err := fs.MkdirAll("/dir/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range [][2]string{{"/dir/sub", "/dir"}, {"/dir", "/dir/sub/dir"}} {
+		if code := move(m[0], m[1], ""); code != http.StatusForbidden {
+			t.Errorf("move %s to %s: status %d, want %d", m[0], m[1], code, http.StatusForbidden)
+		}
+	}
+	if got := readFile(t, fs, "/dir/c.txt"); got != "b" {
+		t.Errorf("file in the refused move = %q", got)
+	}
+	if info, err := fs.Stat("/dir/sub"); err != nil || !info.IsDir() {
+		t.Errorf("refused move removed its source: %v", err)
+	}
+
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if !strings.Contains(w.Header().Get("Allow"), "PROPFIND") || w.Header().Get("DAV") == "" {
+		t.Errorf("OPTIONS headers = %v", w.Header())
+	}
+}
+
+func TestWebDAVMoveKeepsDestination(t *testing.T) {
+	files := map[string]string{"/a.txt": "a", "/b.html": "b", "/private.txt": "p"}
+	for _, filer := range []absfs.Filer{
+		newMemFS(t, files),
+		&renameFS{Filer: newMemFS(t, files), renamed: map[string]string{}},
+	} {
+		fs := httpfs.New(filer, httpfs.WithAllowedExtensions(".txt"), httpfs.WithDenyGlobs("/private.txt"))
+		h := fs.WebDAVHandler()
+		for _, dst := range []string{"/b.html", "/private.txt"} {
+			r := httptest.NewRequest("MOVE", "/a.txt", nil)
+			r.Header.Set("Destination", dst)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%T: refused move to %s: status %d", filer, dst, w.Code)
+			}
+			if got := readFile(t, fs, dst); got != files[dst] {
+				t.Errorf("%T: destination %s of a refused move = %q", filer, dst, got)
+			}
+		}
+		if got := readFile(t, fs, "/a.txt"); got != "a" {
+			t.Errorf("%T: source of a refused move = %q", filer, got)
+		}
+		if infos, err := fs.ReadDir("/"); err != nil || len(infos) != len(files) {
+			t.Errorf("%T: ReadDir after refused moves = %d entries, %v", filer, len(infos), err)
+		}
+	}
+}